@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"proxypool/configs"
+)
+
+const adminUsage = `usage: proxypool admin <command>
+
+commands:
+  status                 paused stages, queues and check workers
+  pause <stage>          stop a stage taking new work (scrape, produce, precheck, check, profiles, resolve)
+  resume <stage>         let a paused stage continue
+  workers <min> [<max>]  set the bounds of the active check workers; min alone pins them
+  scrape [<source>]      scrape one source, or all, now
+  check <id>             check a proxy now and print the result
+`
+
+// admin sends one command to the admin API of the instance at ADMIN_URL and
+// prints the JSON answer.
+func admin(cfg *configs.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, adminUsage)
+		os.Exit(2)
+	}
+
+	method, path, q := "POST", "", url.Values{}
+	switch cmd, rest := args[0], args[1:]; {
+	case cmd == "status" && len(rest) == 0:
+		method, path = "GET", "/admin/status"
+	case (cmd == "pause" || cmd == "resume") && len(rest) == 1:
+		path = "/admin/stages/" + url.PathEscape(rest[0]) + "/" + cmd
+	case cmd == "workers" && (len(rest) == 1 || len(rest) == 2):
+		path = "/admin/workers"
+		q.Set("min", rest[0])
+		if len(rest) == 2 {
+			q.Set("max", rest[1])
+		}
+	case cmd == "scrape" && len(rest) <= 1:
+		path = "/admin/scrape"
+		if len(rest) == 1 {
+			q.Set("source", rest[0])
+		}
+	case cmd == "check" && len(rest) == 1:
+		path = "/admin/proxies/" + url.PathEscape(rest[0]) + "/check"
+	default:
+		fmt.Fprint(os.Stderr, adminUsage)
+		os.Exit(2)
+	}
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, cfg.AdminURL+path, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bad request: %v\n", err)
+		os.Exit(1)
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.AdminToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin request failed: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(os.Stdout, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "%s\n", resp.Status)
+		os.Exit(1)
+	}
+}
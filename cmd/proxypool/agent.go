@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"proxypool/configs"
+	"proxypool/internal/agent"
+	"proxypool/internal/ipfilter"
+)
+
+// runAgent checks proxies for the central instance at AGENT_SERVER_URL and
+// reports the results for AGENT_REGION. It uses the same check settings as
+// the engine.
+func runAgent(cfg *configs.Config) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	denyList, err := ipfilter.ParsePrefixes(cfg.DenyCIDRs)
+	if err != nil {
+		slog.Error("Invalid DENY_CIDRS", "error", err)
+		os.Exit(1)
+	}
+
+	chk, err := newChecker(cfg, ipfilter.New(denyList))
+	if err != nil {
+		slog.Error("Invalid check pipeline", "error", err)
+		os.Exit(1)
+	}
+
+	a := agent.New(cfg.AgentServerURL, cfg.AgentToken, cfg.AgentRegion, chk)
+	a.Workers = cfg.AgentWorkers
+	a.BatchSize = min(cfg.AgentWorkers, 1000) // The most the server hands out at once
+
+	slog.Info("Starting checker agent", "server", cfg.AgentServerURL, "region", cfg.AgentRegion, "workers", cfg.AgentWorkers)
+	a.Run(ctx)
+	slog.Info("Agent stopped")
+}
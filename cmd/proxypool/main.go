@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"proxypool/configs"
+	"proxypool/internal/api"
 	"proxypool/internal/checker"
 	"proxypool/internal/engine"
 	"proxypool/internal/geoip"
+	"proxypool/internal/ipfilter"
+	"proxypool/internal/judge"
+	"proxypool/internal/leader"
 	"proxypool/internal/scraper"
 	"proxypool/internal/scraper/sources"
 	"proxypool/internal/storage"
@@ -22,15 +29,48 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
-	// 2. Load Config
-	cfg, err := configs.Load()
+	// 2. Load Config (agents run without a database)
+	cmd := command()
+	load := configs.Load
+	switch cmd {
+	case "agent":
+		load = configs.LoadAgent
+	case "admin":
+		load = configs.LoadAdmin
+	}
+	cfg, err := load()
 	if err != nil {
 		slog.Error("Failed to load config", "error", err)
 		os.Exit(1)
 	}
 
+	switch cmd {
+	case "run":
+		run(cfg)
+	case "reenrich":
+		reenrich(cfg)
+	case "agent":
+		runAgent(cfg)
+	case "admin":
+		admin(cfg, os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nusage: proxypool [run|reenrich|agent|admin]\n", cmd)
+		os.Exit(2)
+	}
+}
+
+// command returns the subcommand, defaulting to "run".
+func command() string {
+	if len(os.Args) > 1 {
+		return os.Args[1]
+	}
+	return "run"
+}
+
+// run starts the scraping/checking engine and the API.
+func run(cfg *configs.Config) {
 	// 3. Init Storage
-	repo, err := storage.NewPostgresRepository(cfg.DatabaseURL)
+	repo, err := openRepository(cfg)
 	if err != nil {
 		slog.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
@@ -61,17 +101,30 @@ func main() {
 		sources.NewGithubRawSource("sunny9577-HTTP", "https://sunny9577.github.io/proxy-scraper/generated/http_proxies.txt", "http"),
 		sources.NewGithubRawSource("sunny9577-SOCKS4", "https://sunny9577.github.io/proxy-scraper/generated/socks4_proxies.txt", "socks4"),
 		sources.NewGithubRawSource("sunny9577-SOCKS5", "https://sunny9577.github.io/proxy-scraper/generated/socks5_proxies.txt", "socks5"),
+		// HTML tables
+		sources.NewHTMLSource("free-proxy-list-HTTP", "https://free-proxy-list.net/", sources.HTMLConfig{
+			Protocol:   "http",
+			Table:      "table.table-striped",
+			IPColumn:   "td:nth-child(1)",
+			PortColumn: "td:nth-child(2)",
+		}),
 	}
 
-	chk := checker.NewChecker("http://google.com", 5*time.Second)
+	for i, u := range cfg.SubscriptionURLs {
+		sourcesList = append(sourcesList, sources.NewSubscriptionSource(fmt.Sprintf("subscription-%d", i+1), u))
+	}
 
-	// Init GeoIP
-	geo, err := geoip.New("data/GeoLite2-City.mmdb")
+	denyList, err := ipfilter.ParsePrefixes(cfg.DenyCIDRs)
 	if err != nil {
-		slog.Warn("GeoIP disabled (DB not found or invalid)", "error", err)
-	} else {
-		defer geo.Close()
-		slog.Info("GeoIP enabled")
+		slog.Error("Invalid DENY_CIDRS", "error", err)
+		os.Exit(1)
+	}
+	filter := ipfilter.New(denyList)
+
+	chk, err := newChecker(cfg, filter)
+	if err != nil {
+		slog.Error("Invalid check pipeline", "error", err)
+		os.Exit(1)
 	}
 
 	// 5. Context for graceful shutdown
@@ -88,16 +141,214 @@ func main() {
 		cancel()
 	}()
 
+	// Init GeoIP, reloading the databases when they change on disk
+	geo, err := newGeoIP(cfg)
+	if err != nil {
+		slog.Warn("GeoIP disabled (DB not found or invalid)", "error", err)
+	} else {
+		defer geo.Close()
+		go geo.Watch(ctx, cfg.GeoIPReloadInterval)
+		slog.Info("GeoIP enabled")
+	}
+
 	// 7. Initialize Engine
+	var profiles []checker.Profile
+	if cfg.CheckProfilesFile != "" {
+		profiles, err = checker.LoadProfiles(cfg.CheckProfilesFile)
+		if err != nil {
+			slog.Error("Invalid CHECK_PROFILES_FILE", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("Loaded check profiles", "count", len(profiles))
+	}
+
+	var lanes []storage.Lane
+	if cfg.CheckLanes != "" {
+		lanes, err = storage.ParseLanes(cfg.CheckLanes)
+		if err != nil {
+			slog.Error("Invalid CHECK_LANES", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	precheck := checker.NewPrechecker(cfg.PrecheckTimeout)
+	precheck.Guard = filter
+
+	// Singleton tasks need a leader once several instances share the database.
+	var elector engine.Leader
+	if cfg.DirectURL != "" {
+		elector = leader.New(cfg.DirectURL, "proxypool-engine")
+	} else {
+		slog.Info("DIRECT_URL not set, running singleton tasks without leader election")
+	}
+
 	eng := engine.New(repo, sourcesList, chk, geo, engine.Config{
-		NumWorkers: 1000,
+		NumWorkers: cfg.CheckWorkers,
+		MinWorkers: cfg.CheckWorkersMin,
+		MaxWorkers: cfg.CheckWorkersMax,
 		BatchSize:  500,
+		Lanes:      lanes,
+		RateLimits: checker.RateLimits{
+			Target: cfg.CheckRateTarget,
+			Subnet: cfg.CheckRateSubnet,
+			ASN:    cfg.CheckRateASN,
+		},
+		Filter:          filter,
+		Precheck:        precheck,
+		PrecheckWorkers: cfg.PrecheckWorkers,
+		Profiles:        profiles,
+		ProfileInterval: cfg.ProfileInterval,
+		ProfileWorkers:  200,
+
+		CheckHistoryRetention: cfg.CheckHistoryRetention,
+		PromoteAfter:          cfg.PromoteAfter,
+		ResolveInterval:       cfg.ResolveInterval,
+		Leader:                elector,
 	})
 
-	// 8. Run Engine
-	slog.Info("Starting ProxyPool Engine", "workers", 1000, "batch_size", 500)
+	// 8. Start API (and the local judge, if enabled)
+	var controller api.Controller
+	if len(cfg.AdminTokens) > 0 {
+		controller = eng
+	}
+	srv := api.NewServer(repo, api.Config{
+		Tokens:         cfg.APITokens,
+		AgentTokens:    cfg.AgentTokens,
+		RegionInterval: cfg.RegionCheckInterval,
+		Admin:          controller,
+		AdminTokens:    cfg.AdminTokens,
+	})
+	go serveHTTP(ctx, "API", cfg.APIAddr, srv.Handler())
+	if cfg.JudgeAddr != "" {
+		go serveHTTP(ctx, "judge", cfg.JudgeAddr, judge.Handler())
+	}
+
+	// 9. Run Engine
+	slog.Info("Starting ProxyPool Engine", "workers", cfg.CheckWorkers, "min_workers", cfg.CheckWorkersMin,
+		"max_workers", cfg.CheckWorkersMax, "precheck_workers", cfg.PrecheckWorkers, "batch_size", 500)
 	// Run blocking until context is cancelled
 	eng.Run(ctx)
-	
+
 	slog.Info("Shutdown complete")
 }
+
+// serveHTTP serves handler on addr until ctx is cancelled.
+func serveHTTP(ctx context.Context, name, addr string, handler http.Handler) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Starting HTTP server", "server", name, "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP server stopped", "server", name, "error", err)
+	}
+}
+
+// openRepository connects to Postgres and applies pending migrations.
+func openRepository(cfg *configs.Config) (*storage.PostgresRepository, error) {
+	var cipher *storage.Cipher
+	if cfg.CredentialsKey != "" {
+		var err error
+		cipher, err = storage.NewCipherFromBase64(cfg.CredentialsKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CREDENTIALS_KEY: %w", err)
+		}
+	} else {
+		slog.Warn("CREDENTIALS_KEY not set, proxies with credentials will be dropped")
+	}
+
+	repo, err := storage.NewPostgresRepository(cfg.DatabaseURL, cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Migrate(context.Background()); err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return repo, nil
+}
+
+// newChecker builds the checker and, if CHECK_PIPELINE is set, its validator pipeline.
+func newChecker(cfg *configs.Config, guard *ipfilter.Filter) (*checker.Checker, error) {
+	chk := checker.NewChecker(cfg.CheckTargetURL, cfg.CheckTimeout)
+	chk.Guard = guard
+	chk.JudgeURL = cfg.JudgeURL
+	chk.HTTPSURL = cfg.CheckHTTPSURL
+	chk.IPv6URL = cfg.CheckIPv6URL
+	chk.Retry = checker.Retry{
+		Attempts: cfg.CheckRetries,
+		Delay:    cfg.CheckRetryDelay,
+		Targets:  cfg.CheckRetryTargets,
+	}
+
+	if cfg.CheckPipeline != "" {
+		payloadHash := cfg.CheckPayloadSHA256
+		if payloadHash == "" {
+			payloadHash = judge.PayloadHash
+		}
+		pipeline, err := checker.NewPipeline(cfg.CheckPipeline, checker.PipelineConfig{
+			TargetURL:    cfg.CheckTargetURL,
+			HTTPSURL:     cfg.CheckHTTPSURL,
+			JudgeURL:     cfg.JudgeURL,
+			ContentURL:   cfg.CheckContentURL,
+			ContentMatch: cfg.CheckContentMatch,
+
+			PayloadURL:     cfg.CheckPayloadURL,
+			PayloadHash:    payloadHash,
+			TLSURL:         cfg.CheckTLSURL,
+			TLSFingerprint: cfg.CheckTLSFingerprint,
+
+			ThroughputURL:     cfg.CheckThroughputURL,
+			MinKBps:           cfg.CheckMinKBps,
+			ThroughputTimeout: cfg.CheckThroughputTimeout,
+
+			IPv6URL: cfg.CheckIPv6URL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		chk.Pipeline = pipeline
+	}
+	return chk, nil
+}
+
+// newGeoIP opens the City database and, if present, the ASN database.
+func newGeoIP(cfg *configs.Config) (*geoip.Service, error) {
+	asnPath := cfg.GeoIPASNDB
+	if _, err := os.Stat(asnPath); err != nil {
+		slog.Warn("ASN enrichment disabled", "path", asnPath, "error", err)
+		asnPath = ""
+	}
+
+	geo, err := geoip.New(cfg.GeoIPCityDB, asnPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hosting, err := geoip.ParseASNs(cfg.HostingASNs)
+	if err != nil {
+		geo.Close()
+		return nil, fmt.Errorf("HOSTING_ASNS: %w", err)
+	}
+	if len(hosting) == 0 {
+		hosting = geoip.DefaultHostingASNs
+	}
+	mobile, err := geoip.ParseASNs(cfg.MobileASNs)
+	if err != nil {
+		geo.Close()
+		return nil, fmt.Errorf("MOBILE_ASNS: %w", err)
+	}
+	geo.Classifier = geoip.NewClassifier(hosting, mobile)
+
+	return geo, nil
+}
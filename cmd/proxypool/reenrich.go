@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"proxypool/configs"
+	"proxypool/internal/engine"
+)
+
+// reenrich re-runs GeoIP enrichment over every stored proxy. Use it after
+// updating the MaxMind databases.
+func reenrich(cfg *configs.Config) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	repo, err := openRepository(cfg)
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer repo.Close()
+
+	geo, err := newGeoIP(cfg)
+	if err != nil {
+		slog.Error("Failed to open GeoIP databases", "error", err)
+		os.Exit(1)
+	}
+	defer geo.Close()
+
+	n, err := engine.Reenrich(ctx, repo, geo, 1000)
+	if err != nil {
+		slog.Error("Re-enrichment failed", "updated", n, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Re-enrichment complete", "updated", n)
+}
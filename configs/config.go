@@ -2,25 +2,427 @@ package configs
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxypool/internal/model"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	DatabaseURL string
+	// DirectURL is a session (non-pooled) connection to the same database (DIRECT_URL,
+	// optional). When set, instances sharing the database elect a leader over it to run
+	// scraping and the other singleton tasks; checking is shared either way.
+	DirectURL string
+
+	// CredentialsKey is the base64 AES-256 key used to encrypt proxy credentials (CREDENTIALS_KEY).
+	// Without it, proxies that need credentials are not stored.
+	CredentialsKey string
+
+	// APIAddr is the listen address of the HTTP API (API_ADDR, default ":8080").
+	APIAddr string
+	// APITokens authorise API consumers (API_TOKENS, comma separated).
+	APITokens []string
+
+	// JudgeURL is fetched through live proxies to learn their exit IP (JUDGE_URL, optional).
+	// It may point at our own judge (JudgeAddr) or a public service like https://api.ipify.org?format=json.
+	JudgeURL string
+	// JudgeAddr, if set, serves the built-in judge on this address (JUDGE_ADDR).
+	// It has to be reachable from the internet.
+	JudgeAddr string
+
+	// DenyCIDRs are extra ranges (beyond private/reserved) never ingested or dialled (DENY_CIDRS, comma separated).
+	DenyCIDRs []string
+
+	// GeoIPCityDB and GeoIPASNDB are MaxMind database paths (GEOIP_CITY_DB, GEOIP_ASN_DB).
+	GeoIPCityDB string
+	GeoIPASNDB  string
+	// GeoIPReloadInterval is how often the database files are checked for updates (GEOIP_RELOAD_INTERVAL, default 1m).
+	GeoIPReloadInterval time.Duration
+	// HostingASNs and MobileASNs classify proxy networks (HOSTING_ASNS, MOBILE_ASNS, comma separated).
+	// Empty HostingASNs means the built-in list.
+	HostingASNs []string
+	MobileASNs  []string
+
+	// CheckPipeline is the ordered list of validators run for every proxy (CHECK_PIPELINE),
+	// e.g. "tcp,handshake,http,https?,anonymity?". A trailing "?" makes a step optional.
+	// Empty means a single HEAD to CheckTargetURL plus exit IP detection when JudgeURL is set.
+	CheckPipeline string
+	// CheckTargetURL is requested through proxies by the http and handshake validators (CHECK_TARGET_URL).
+	CheckTargetURL string
+	// CheckHTTPSURL is requested through proxies to test TLS tunnelling (CHECK_HTTPS_URL,
+	// optional). Setting it, e.g. to https://www.google.com, adds a CONNECT and TLS
+	// handshake to every check of the default pipeline and fills supports_https; with
+	// CHECK_PIPELINE, list "https" (or "https?") to use it.
+	CheckHTTPSURL string
+	// CheckIPv6URL is an IPv6-only URL requested through proxies to learn whether they
+	// reach IPv6 targets (CHECK_IPV6_URL, optional), e.g. http://api6.ipify.org. Setting
+	// it adds that request to every check of the default pipeline and fills supports_ipv6;
+	// with CHECK_PIPELINE, list "ipv6?" to use it.
+	CheckIPv6URL string
+	// CheckContentURL and CheckContentMatch configure the content validator: the page
+	// body must contain the match string (CHECK_CONTENT_URL, CHECK_CONTENT_MATCH).
+	CheckContentURL   string
+	CheckContentMatch string
+	// CheckPayloadURL and CheckPayloadSHA256 configure the integrity validator: a page with a
+	// known hash, normally the judge's /payload (CHECK_PAYLOAD_URL, CHECK_PAYLOAD_SHA256).
+	// The hash defaults to that of the built-in judge payload.
+	CheckPayloadURL    string
+	CheckPayloadSHA256 string
+	// CheckTLSURL is fetched through the tunnel by the integrity validator to spot forged
+	// certificates (CHECK_TLS_URL, optional). CheckTLSFingerprint optionally pins the hex
+	// SHA-256 of its leaf certificate (CHECK_TLS_FINGERPRINT).
+	CheckTLSURL         string
+	CheckTLSFingerprint string
+	// CheckThroughputURL is downloaded through proxies by the throughput validator, e.g. the
+	// judge's /bytes?n=5242880 (CHECK_THROUGHPUT_URL). CheckMinKBps fails proxies slower
+	// than this (CHECK_MIN_KBPS, optional). The download has its own deadline outside
+	// CheckTimeout (CHECK_THROUGHPUT_TIMEOUT, default 10s).
+	CheckThroughputURL     string
+	CheckMinKBps           int
+	CheckThroughputTimeout time.Duration
+	// CheckTimeout bounds the whole pipeline for one proxy (CHECK_TIMEOUT, default 5s).
+	CheckTimeout time.Duration
+	// CheckRetries is how often a check that failed for a possibly transient reason is
+	// re-run before the proxy counts as dead (CHECK_RETRIES, default 1), after
+	// CheckRetryDelay (CHECK_RETRY_DELAY, default 2s). Retries go to CheckRetryTargets in
+	// turn instead of CheckTargetURL, if set (CHECK_RETRY_TARGETS, comma separated).
+	CheckRetries      int
+	CheckRetryDelay   time.Duration
+	CheckRetryTargets []string
+	// PromoteAfter is how many consecutive passed checks a new proxy needs before it
+	// is served (PROMOTE_AFTER, default 2).
+	PromoteAfter int
+	// CheckProfilesFile is a JSON file of named check profiles run against alive proxies
+	// (CHECK_PROFILES_FILE, optional). See checker.LoadProfiles for the format.
+	CheckProfilesFile string
+	// ProfileInterval is how long a profile result stays fresh (PROFILE_INTERVAL, default 30m).
+	ProfileInterval time.Duration
+	// CheckHistoryRetention is how long per-check history with timings is kept
+	// (CHECK_HISTORY_RETENTION, default 168h).
+	CheckHistoryRetention time.Duration
+	// CheckWorkers is the number of concurrent checks to start with (CHECK_WORKERS, default 1000).
+	// The engine adapts it between CheckWorkersMin and CheckWorkersMax (CHECK_WORKERS_MIN,
+	// CHECK_WORKERS_MAX, default 100 and 2000, widened to include CheckWorkers) as
+	// timeouts, throughput and open files allow.
+	CheckWorkers    int
+	CheckWorkersMin int
+	CheckWorkersMax int
+	// CheckLanes weights the check lanes and sets how long their proxies rest between
+	// checks (CHECK_LANES), e.g. "serving=4/5m,new=3,recent=2/15m,dead=1/1h", which is
+	// also the default. See storage.ParseLanes.
+	CheckLanes string
+	// CheckRateTarget, CheckRateSubnet and CheckRateASN cap the checks per second against
+	// one target host, from one proxy /24 (/48 for IPv6) and from one ASN
+	// (CHECK_RATE_TARGET, CHECK_RATE_SUBNET, CHECK_RATE_ASN, optional). Profiles set
+	// their own in the profiles file.
+	CheckRateTarget float64
+	CheckRateSubnet float64
+	CheckRateASN    float64
+	// PrecheckTimeout is the TCP connect timeout of the pre-check that filters out
+	// closed ports before the full check (PRECHECK_TIMEOUT, default 1s).
+	PrecheckTimeout time.Duration
+	// PrecheckWorkers is the number of concurrent pre-checks (PRECHECK_WORKERS, default
+	// 4000). The engine lowers it if the open file limit can't also hold CheckWorkersMax.
+	PrecheckWorkers int
+
+	// ResolveInterval is how often proxies listed by hostname are resolved again
+	// (RESOLVE_INTERVAL, default 5m).
+	ResolveInterval time.Duration
+
+	// SubscriptionURLs are Clash/V2Ray subscription feeds to import (SUBSCRIPTION_URLS, comma separated).
+	SubscriptionURLs []string
+
+	// AgentTokens authorise checker agents in other regions to pull work and report
+	// results (AGENT_TOKENS, comma separated). RegionCheckInterval is how long a result
+	// from a region stays fresh (REGION_CHECK_INTERVAL, default 15m).
+	AgentTokens         []string
+	RegionCheckInterval time.Duration
+
+	// AgentServerURL, AgentToken and AgentRegion configure "proxypool agent": the central
+	// instance's API to pull work from, the token to use and the region to report for
+	// (AGENT_SERVER_URL, AGENT_TOKEN, AGENT_REGION). AgentWorkers is the number of
+	// concurrent checks (AGENT_WORKERS, default 100).
+	AgentServerURL string
+	AgentToken     string
+	AgentRegion    string
+	AgentWorkers   int
+
+	// AdminTokens authorise the admin API that pauses stages, scrapes and checks on demand
+	// (ADMIN_TOKENS, comma separated; the API is off without them).
+	AdminTokens []string
+	// AdminURL and AdminToken are what "proxypool admin" talks to and authenticates with
+	// (ADMIN_URL, default "http://localhost:8080", and ADMIN_TOKEN).
+	AdminURL   string
+	AdminToken string
 }
 
+// Load reads the configuration of the central instance, which needs the database.
 func Load() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is not set")
+	}
+	return cfg, nil
+}
+
+// LoadAgent reads the configuration of a checker agent, which needs no database but
+// the central instance to work for.
+func LoadAgent() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AgentServerURL == "" || cfg.AgentToken == "" {
+		return nil, fmt.Errorf("AGENT_SERVER_URL and AGENT_TOKEN must be set")
+	}
+	if !model.ValidRegion(cfg.AgentRegion) {
+		return nil, fmt.Errorf("AGENT_REGION: invalid region %q", cfg.AgentRegion)
+	}
+	return cfg, nil
+}
+
+// LoadAdmin reads the configuration of the admin CLI, which only talks to a running
+// instance.
+func LoadAdmin() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AdminToken == "" {
+		return nil, fmt.Errorf("ADMIN_TOKEN is not set")
+	}
+	return cfg, nil
+}
+
+func load() (*Config, error) {
 	// Try loading .env, but don't fail if it doesn't exist (e.g. production)
 	_ = godotenv.Load()
 
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL is not set")
+	geoReload, err := getDuration("GEOIP_RELOAD_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	checkTimeout, err := getDuration("CHECK_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	precheckTimeout, err := getDuration("PRECHECK_TIMEOUT", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	precheckWorkers, err := getInt("PRECHECK_WORKERS", 4000)
+	if err != nil {
+		return nil, err
+	}
+
+	profileInterval, err := getDuration("PROFILE_INTERVAL", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	minKBps, err := getInt("CHECK_MIN_KBPS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	throughputTimeout, err := getDuration("CHECK_THROUGHPUT_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	historyRetention, err := getDuration("CHECK_HISTORY_RETENTION", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	retries, err := getInt("CHECK_RETRIES", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	retryDelay, err := getDuration("CHECK_RETRY_DELAY", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	promoteAfter, err := getInt("PROMOTE_AFTER", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	resolveInterval, err := getDuration("RESOLVE_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	workers, err := getInt("CHECK_WORKERS", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unset bounds make room for CHECK_WORKERS, so setting only that keeps working.
+	workersMin, err := getInt("CHECK_WORKERS_MIN", min(100, workers))
+	if err != nil {
+		return nil, err
+	}
+
+	workersMax, err := getInt("CHECK_WORKERS_MAX", max(2000, workers))
+	if err != nil {
+		return nil, err
+	}
+	if workersMin > workers || workers > workersMax {
+		return nil, fmt.Errorf("CHECK_WORKERS must be between CHECK_WORKERS_MIN and CHECK_WORKERS_MAX")
+	}
+
+	rateTarget, err := getFloat("CHECK_RATE_TARGET", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rateSubnet, err := getFloat("CHECK_RATE_SUBNET", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rateASN, err := getFloat("CHECK_RATE_ASN", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	regionInterval, err := getDuration("REGION_CHECK_INTERVAL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	agentWorkers, err := getInt("AGENT_WORKERS", 100)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Config{
-		DatabaseURL: dbURL,
+		DatabaseURL:            os.Getenv("DATABASE_URL"),
+		DirectURL:              os.Getenv("DIRECT_URL"),
+		CredentialsKey:         os.Getenv("CREDENTIALS_KEY"),
+		APIAddr:                getString("API_ADDR", ":8080"),
+		APITokens:              getList("API_TOKENS"),
+		JudgeURL:               os.Getenv("JUDGE_URL"),
+		JudgeAddr:              os.Getenv("JUDGE_ADDR"),
+		DenyCIDRs:              getList("DENY_CIDRS"),
+		GeoIPCityDB:            getString("GEOIP_CITY_DB", "data/GeoLite2-City.mmdb"),
+		GeoIPASNDB:             getString("GEOIP_ASN_DB", "data/GeoLite2-ASN.mmdb"),
+		GeoIPReloadInterval:    geoReload,
+		HostingASNs:            getList("HOSTING_ASNS"),
+		MobileASNs:             getList("MOBILE_ASNS"),
+		CheckPipeline:          os.Getenv("CHECK_PIPELINE"),
+		CheckTargetURL:         getString("CHECK_TARGET_URL", "http://google.com"),
+		CheckHTTPSURL:          os.Getenv("CHECK_HTTPS_URL"),
+		CheckIPv6URL:           os.Getenv("CHECK_IPV6_URL"),
+		CheckContentURL:        os.Getenv("CHECK_CONTENT_URL"),
+		CheckContentMatch:      os.Getenv("CHECK_CONTENT_MATCH"),
+		CheckPayloadURL:        os.Getenv("CHECK_PAYLOAD_URL"),
+		CheckPayloadSHA256:     os.Getenv("CHECK_PAYLOAD_SHA256"),
+		CheckTLSURL:            os.Getenv("CHECK_TLS_URL"),
+		CheckTLSFingerprint:    os.Getenv("CHECK_TLS_FINGERPRINT"),
+		CheckThroughputURL:     os.Getenv("CHECK_THROUGHPUT_URL"),
+		CheckMinKBps:           minKBps,
+		CheckThroughputTimeout: throughputTimeout,
+		CheckTimeout:           checkTimeout,
+		CheckRetries:           retries,
+		CheckRetryDelay:        retryDelay,
+		CheckRetryTargets:      getList("CHECK_RETRY_TARGETS"),
+		PromoteAfter:           promoteAfter,
+		CheckProfilesFile:      os.Getenv("CHECK_PROFILES_FILE"),
+		ProfileInterval:        profileInterval,
+		CheckHistoryRetention:  historyRetention,
+		CheckWorkers:           workers,
+		CheckLanes:             os.Getenv("CHECK_LANES"),
+		CheckWorkersMin:        workersMin,
+		CheckWorkersMax:        workersMax,
+		CheckRateTarget:        rateTarget,
+		CheckRateSubnet:        rateSubnet,
+		CheckRateASN:           rateASN,
+		PrecheckTimeout:        precheckTimeout,
+		PrecheckWorkers:        precheckWorkers,
+		ResolveInterval:        resolveInterval,
+		SubscriptionURLs:       getList("SUBSCRIPTION_URLS"),
+		AgentTokens:            getList("AGENT_TOKENS"),
+		RegionCheckInterval:    regionInterval,
+		AgentServerURL:         strings.TrimSuffix(os.Getenv("AGENT_SERVER_URL"), "/"),
+		AgentToken:             os.Getenv("AGENT_TOKEN"),
+		AgentRegion:            os.Getenv("AGENT_REGION"),
+		AgentWorkers:           max(1, agentWorkers),
+		AdminTokens:            getList("ADMIN_TOKENS"),
+		AdminURL:               strings.TrimSuffix(getString("ADMIN_URL", "http://localhost:8080"), "/"),
+		AdminToken:             os.Getenv("ADMIN_TOKEN"),
 	}, nil
 }
+
+// getString reads an env var, falling back to def if unset.
+func getString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// getDuration reads a duration env var ("30s", "5m"), falling back to def if unset.
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", key, v)
+	}
+	return d, nil
+}
+
+// getInt reads a non-negative integer env var, falling back to def if unset.
+func getInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s: invalid number %q", key, v)
+	}
+	return n, nil
+}
+
+// getFloat reads a non-negative decimal env var, falling back to def if unset.
+func getFloat(key string, def float64) (float64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("%s: invalid number %q", key, v)
+	}
+	return f, nil
+}
+
+// getList reads a comma separated env var, dropping empty items.
+func getList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+// Package agent runs checks on behalf of a central proxypool from another
+// region: it pulls proxies from the central agent API, checks them locally
+// and reports the results tagged with its region. The wire types are shared
+// with the API that serves them.
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"proxypool/internal/checker"
+	"proxypool/internal/model"
+)
+
+// Paths of the central agent API.
+const (
+	JobsPath    = "/agent/jobs"    // POST ?region=&limit=, returns []Job
+	ResultsPath = "/agent/results" // POST Report
+)
+
+// Job is a proxy handed to an agent to check. It carries the credentials,
+// so the agent API must only be served over TLS.
+type Job struct {
+	ID       int64  `json:"id"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// NewJob returns the job for checking p.
+func NewJob(p *model.Proxy) Job {
+	return Job{
+		ID:       p.ID,
+		IP:       p.IP,
+		Port:     p.Port,
+		Protocol: p.Protocol,
+		Username: p.Username,
+		Password: p.Password,
+	}
+}
+
+// Proxy returns the proxy to check.
+func (j Job) Proxy() *model.Proxy {
+	return &model.Proxy{
+		ID:       j.ID,
+		IP:       j.IP,
+		Port:     j.Port,
+		Protocol: j.Protocol,
+		Username: j.Username,
+		Password: j.Password,
+	}
+}
+
+// Report is what an agent sends back for a batch of jobs.
+type Report struct {
+	Region  string   `json:"region"`
+	Results []Result `json:"results"`
+}
+
+// Result is the outcome of one job.
+type Result struct {
+	ProxyID   int64  `json:"proxy_id"`
+	Alive     bool   `json:"alive"`
+	LatencyMS int    `json:"latency_ms,omitempty"`
+	Failure   string `json:"failure,omitempty"` // checker.Failure* class if not alive
+}
+
+// Agent pulls jobs from ServerURL, authenticating with Token, checks them
+// with Checker on Workers goroutines and reports them for Region.
+type Agent struct {
+	ServerURL string
+	Token     string
+	Region    string
+	Checker   *checker.Checker
+
+	Workers   int
+	BatchSize int
+
+	// Idle is how long to wait before asking again when there was no work
+	// or the server couldn't be reached.
+	Idle time.Duration
+
+	Client *http.Client
+}
+
+func New(serverURL, token, region string, chk *checker.Checker) *Agent {
+	return &Agent{
+		ServerURL: serverURL,
+		Token:     token,
+		Region:    region,
+		Checker:   chk,
+		Workers:   100,
+		BatchSize: 100,
+		Idle:      30 * time.Second,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run pulls, checks and reports batches until ctx is cancelled. Jobs of a
+// batch interrupted by shutdown aren't reported; the server hands them out
+// again once their claim expires.
+func (a *Agent) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := a.fetchJobs(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Fetching jobs failed", "error", err)
+		}
+		if len(jobs) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(a.Idle):
+			}
+			continue
+		}
+
+		results := a.checkAll(ctx, jobs)
+		if ctx.Err() != nil {
+			return
+		}
+		if err := a.report(ctx, results); err != nil {
+			slog.Error("Reporting results failed", "count", len(results), "error", err)
+			continue
+		}
+
+		alive := 0
+		for _, r := range results {
+			if r.Alive {
+				alive++
+			}
+		}
+		slog.Info("Checked jobs", "region", a.Region, "count", len(results), "alive", alive)
+	}
+}
+
+// checkAll checks the jobs on up to Workers goroutines.
+func (a *Agent) checkAll(ctx context.Context, jobs []Job) []Result {
+	results := make([]Result, len(jobs))
+	sem := make(chan struct{}, max(1, a.Workers))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = a.check(ctx, job)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (a *Agent) check(ctx context.Context, job Job) Result {
+	res, err := a.Checker.Check(ctx, job.Proxy())
+	if err != nil {
+		return Result{ProxyID: job.ID, Failure: checker.FailureOther}
+	}
+	if !res.Alive {
+		return Result{ProxyID: job.ID, Failure: res.Failure}
+	}
+	return Result{ProxyID: job.ID, Alive: true, LatencyMS: res.LatencyMS}
+}
+
+func (a *Agent) fetchJobs(ctx context.Context) ([]Job, error) {
+	q := url.Values{"region": {a.Region}, "limit": {strconv.Itoa(a.BatchSize)}}
+	var jobs []Job
+	if err := a.post(ctx, JobsPath+"?"+q.Encode(), nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (a *Agent) report(ctx context.Context, results []Result) error {
+	body, err := json.Marshal(Report{Region: a.Region, Results: results})
+	if err != nil {
+		return err
+	}
+	return a.post(ctx, ResultsPath, body, nil)
+}
+
+// post sends body to the server at path and decodes the response into out,
+// if not nil.
+func (a *Agent) post(ctx context.Context, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", a.ServerURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("bad request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: status %d: %s", path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: decode: %w", path, err)
+	}
+	return nil
+}
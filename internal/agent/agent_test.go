@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"proxypool/internal/checker"
+)
+
+func TestAgent_Run(t *testing.T) {
+	// An HTTP proxy that answers every request itself, and a port nobody listens on.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyPort, _ := strconv.Atoi(proxyURL.Port())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		served  bool
+		reports []Report
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer agent-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case JobsPath:
+			if r.URL.Query().Get("region") != "eu-west" {
+				t.Errorf("Jobs asked for region %q", r.URL.Query().Get("region"))
+			}
+			var jobs []Job
+			if !served {
+				served = true
+				jobs = []Job{
+					{ID: 1, IP: "127.0.0.1", Port: proxyPort, Protocol: "http"},
+					{ID: 2, IP: "127.0.0.1", Port: deadPort, Protocol: "http"},
+				}
+			}
+			json.NewEncoder(w).Encode(jobs)
+		case ResultsPath:
+			var report Report
+			if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+				t.Errorf("Decoding report failed: %v", err)
+			}
+			reports = append(reports, report)
+			cancel()
+		}
+	}))
+	defer server.Close()
+
+	a := New(server.URL, "agent-secret", "eu-west", checker.NewChecker("http://target.test/", 2*time.Second))
+	a.Idle = 10 * time.Millisecond
+	a.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+	report := reports[0]
+	if report.Region != "eu-west" || len(report.Results) != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if r := report.Results[0]; r.ProxyID != 1 || !r.Alive || r.LatencyMS <= 0 {
+		t.Errorf("Expected proxy 1 alive, got %+v", r)
+	}
+	if r := report.Results[1]; r.ProxyID != 2 || r.Alive || r.Failure != checker.FailureRefused {
+		t.Errorf("Expected proxy 2 refused, got %+v", r)
+	}
+}
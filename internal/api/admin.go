@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"proxypool/internal/engine"
+	"proxypool/internal/storage"
+)
+
+// Controller is the running engine as the admin API drives it; see
+// engine.Engine.
+type Controller interface {
+	Status() engine.Status
+	Pause(stage string) error
+	Resume(stage string) error
+	SetWorkers(min, max int) error
+	Scrape(ctx context.Context, source string) (map[string]int, error)
+	CheckProxy(ctx context.Context, id int64) (*engine.CheckReport, error)
+}
+
+func (s *Server) adminRoutes() {
+	s.mux.HandleFunc("GET /admin/status", s.admin(s.handleAdminStatus))
+	s.mux.HandleFunc("POST /admin/stages/{stage}/pause", s.admin(s.handleAdminPause))
+	s.mux.HandleFunc("POST /admin/stages/{stage}/resume", s.admin(s.handleAdminResume))
+	s.mux.HandleFunc("POST /admin/workers", s.admin(s.handleAdminWorkers))
+	s.mux.HandleFunc("POST /admin/scrape", s.admin(s.handleAdminScrape))
+	s.mux.HandleFunc("POST /admin/proxies/{id}/check", s.admin(s.handleAdminCheck))
+}
+
+// admin wraps an admin handler with the admin token check. Admin tokens
+// are only accepted as "Authorization: Bearer <token>".
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasBearer(r, s.cfg.AdminTokens) {
+			writeError(w, http.StatusUnauthorized, "admin token required")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handleAdminStatus serves GET /admin/status: paused stages, queue lengths
+// and check workers.
+func (s *Server) handleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.cfg.Admin.Status())
+}
+
+// handleAdminPause serves POST /admin/stages/{stage}/pause.
+func (s *Server) handleAdminPause(w http.ResponseWriter, r *http.Request) {
+	if err := s.cfg.Admin.Pause(r.PathValue("stage")); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cfg.Admin.Status())
+}
+
+// handleAdminResume serves POST /admin/stages/{stage}/resume.
+func (s *Server) handleAdminResume(w http.ResponseWriter, r *http.Request) {
+	if err := s.cfg.Admin.Resume(r.PathValue("stage")); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cfg.Admin.Status())
+}
+
+// handleAdminWorkers serves POST /admin/workers?min=&max=, setting the
+// bounds the active check workers adapt within. Omitting max pins the
+// number at min.
+func (s *Server) handleAdminWorkers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lo, err := strconv.Atoi(q.Get("min"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid min")
+		return
+	}
+	hi := lo
+	if v := q.Get("max"); v != "" {
+		if hi, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid max")
+			return
+		}
+	}
+	if err := s.cfg.Admin.SetWorkers(lo, hi); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.cfg.Admin.Status())
+}
+
+// handleAdminScrape serves POST /admin/scrape?source=: it scrapes one source,
+// or all of them, and answers once done with the proxies saved per source.
+func (s *Server) handleAdminScrape(w http.ResponseWriter, r *http.Request) {
+	saved, err := s.cfg.Admin.Scrape(r.Context(), r.URL.Query().Get("source"))
+	if errors.Is(err, engine.ErrUnknownSource) {
+		writeAdminError(w, err)
+		return
+	}
+	resp := map[string]any{"saved": saved}
+	status := http.StatusOK
+	if err != nil {
+		resp["error"] = err.Error()
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, resp)
+}
+
+// handleAdminCheck serves POST /admin/proxies/{id}/check: it checks the
+// proxy now and answers with the result, which is saved as usual.
+func (s *Server) handleAdminCheck(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	report, err := s.cfg.Admin.CheckProxy(r.Context(), id)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// writeAdminError maps engine and storage errors to statuses.
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrUnknownStage), errors.Is(err, engine.ErrUnknownSource), errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, engine.ErrWorkerBounds):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, storage.ErrStale):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		slog.Error("Admin request failed", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"proxypool/internal/agent"
+	"proxypool/internal/model"
+)
+
+const (
+	// maxAgentJobs bounds a batch of jobs.
+	maxAgentJobs = 1000
+	// maxReportBody bounds a report of results.
+	maxReportBody = 1 << 20
+	// maxFailureLen bounds a reported failure class.
+	maxFailureLen = 64
+)
+
+// handleAgentJobs serves POST /agent/jobs?region=&limit=: it claims alive
+// proxies due for a check from the region and returns them as jobs,
+// credentials included.
+func (s *Server) handleAgentJobs(w http.ResponseWriter, r *http.Request) {
+	if !s.agentAuthorized(r) {
+		writeError(w, http.StatusUnauthorized, "agent token required")
+		return
+	}
+
+	q := r.URL.Query()
+	region := q.Get("region")
+	if !model.ValidRegion(region) {
+		writeError(w, http.StatusBadRequest, "invalid region")
+		return
+	}
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAgentJobs {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	proxies, err := s.repo.GetProxiesForRegion(r.Context(), region, time.Now().Add(-s.cfg.RegionInterval), limit)
+	if err != nil {
+		slog.Error("Fetching agent jobs failed", "region", region, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to fetch jobs")
+		return
+	}
+
+	jobs := make([]agent.Job, 0, len(proxies))
+	for _, p := range proxies {
+		jobs = append(jobs, agent.NewJob(p))
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleAgentResults serves POST /agent/results, storing an agent.Report.
+func (s *Server) handleAgentResults(w http.ResponseWriter, r *http.Request) {
+	if !s.agentAuthorized(r) {
+		writeError(w, http.StatusUnauthorized, "agent token required")
+		return
+	}
+
+	var report agent.Report
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReportBody)).Decode(&report); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid report")
+		return
+	}
+	if !model.ValidRegion(report.Region) {
+		writeError(w, http.StatusBadRequest, "invalid region")
+		return
+	}
+
+	now := time.Now()
+	results := make([]*model.RegionResult, 0, len(report.Results))
+	for _, res := range report.Results {
+		if res.ProxyID <= 0 || res.LatencyMS < 0 || (res.Alive && res.LatencyMS == 0) || len(res.Failure) > maxFailureLen {
+			writeError(w, http.StatusBadRequest, "invalid result")
+			return
+		}
+		results = append(results, &model.RegionResult{
+			ProxyID:   res.ProxyID,
+			Region:    report.Region,
+			Alive:     res.Alive,
+			LatencyMS: res.LatencyMS,
+			Failure:   res.Failure,
+			CheckedAt: now,
+		})
+	}
+
+	if err := s.repo.SaveRegionResults(r.Context(), results); err != nil {
+		slog.Error("Saving agent results failed", "region", report.Region, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to save results")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"saved": len(results)})
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxypool/internal/model"
+	"proxypool/internal/storage"
+)
+
+type proxyResponse struct {
+	IP             string     `json:"ip"`
+	Host           string     `json:"host,omitempty"` // Listed hostname; IP is its current resolution
+	Port           int        `json:"port"`
+	Protocol       string     `json:"protocol"`
+	ExitIP         string     `json:"exit_ip,omitempty"`
+	MultiHop       bool       `json:"multi_hop"`
+	Country        string     `json:"country"`
+	City           string     `json:"city"`
+	Region         string     `json:"region"`
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	ASN            int        `json:"asn"`
+	ASNOrg         string     `json:"asn_org"`
+	NetworkType    string     `json:"network_type"`
+	Anonymity      string     `json:"anonymity"`
+	SupportsHTTPS  *bool      `json:"supports_https"`
+	SupportsIPv6   *bool      `json:"supports_ipv6"`
+	Tampered       bool       `json:"tampered"`
+	TamperReason   string     `json:"tamper_reason,omitempty"`
+	LatencyMS      int        `json:"latency_ms"`
+	RegionLatency  int        `json:"region_latency_ms,omitempty"` // Latency from check_region, if asked for
+	ThroughputKBps int        `json:"throughput_kbps,omitempty"`
+	LastCheckedAt  *time.Time `json:"last_checked_at"`
+	URL            string     `json:"url"`
+	Username       string     `json:"username,omitempty"`
+	Password       string     `json:"password,omitempty"`
+}
+
+func newProxyResponse(p *model.Proxy, withAuth bool) proxyResponse {
+	resp := proxyResponse{
+		IP:             p.IP,
+		Host:           p.Host,
+		Port:           p.Port,
+		Protocol:       p.Protocol,
+		ExitIP:         p.ExitIP,
+		MultiHop:       p.MultiHop(),
+		Country:        p.Country,
+		City:           p.City,
+		Region:         p.Region,
+		Latitude:       p.Latitude,
+		Longitude:      p.Longitude,
+		ASN:            p.ASN,
+		ASNOrg:         p.ASNOrg,
+		NetworkType:    p.NetworkType,
+		Anonymity:      p.Anonymity,
+		SupportsHTTPS:  p.SupportsHTTPS,
+		SupportsIPv6:   p.SupportsIPv6,
+		Tampered:       p.Tampered,
+		TamperReason:   p.TamperReason,
+		LatencyMS:      p.LatencyMS,
+		RegionLatency:  p.RegionLatencyMS,
+		ThroughputKBps: p.ThroughputKBps,
+		LastCheckedAt:  p.LastCheckedAt,
+		URL:            p.URL(),
+	}
+	if withAuth && p.HasAuth() {
+		resp.URL = p.AuthURL()
+		resp.Username = p.Username
+		resp.Password = p.Password
+	}
+	return resp
+}
+
+// handleListProxies serves GET /proxies.
+//
+// Query parameters: protocol, country, asn, network_type, exit_ip, https,
+// ipv6 (can reach IPv6 targets), ip_version (4|6, entry address family),
+// profile (proxies whose latest run of that check profile passed),
+// check_region (proxies alive from that region's checker agents, ranked by
+// the latency measured there),
+// distinct_exit (one proxy per exit IP), include_tampered (also return
+// proxies caught modifying traffic), min_kbps, sort (latency|throughput|score),
+// limit, format (json|txt).
+func (s *Server) handleListProxies(w http.ResponseWriter, r *http.Request) {
+	authorized := s.authorized(r)
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.IncludeAuth = authorized
+
+	proxies, err := s.repo.List(r.Context(), filter)
+	if err != nil {
+		slog.Error("List proxies failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list proxies")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "txt" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, p := range proxies {
+			u := p.URL()
+			if authorized {
+				u = p.AuthURL()
+			}
+			fmt.Fprintln(w, u)
+		}
+		return
+	}
+
+	resp := make([]proxyResponse, 0, len(proxies))
+	for _, p := range proxies {
+		resp = append(resp, newProxyResponse(p, authorized))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseFilter(r *http.Request) (storage.ProxyFilter, error) {
+	q := r.URL.Query()
+	f := storage.ProxyFilter{
+		Protocol:    q.Get("protocol"),
+		Country:     q.Get("country"),
+		NetworkType: q.Get("network_type"),
+		Profile:     q.Get("profile"),
+		CheckRegion: q.Get("check_region"),
+		Sort:        q.Get("sort"),
+	}
+	if !storage.ValidSort(f.Sort) {
+		return f, fmt.Errorf("invalid sort %q", f.Sort)
+	}
+	if f.CheckRegion != "" && !model.ValidRegion(f.CheckRegion) {
+		return f, fmt.Errorf("invalid check_region %q", f.CheckRegion)
+	}
+	if v := q.Get("min_kbps"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("invalid min_kbps %q", v)
+		}
+		f.MinKBps = n
+	}
+	if v := q.Get("exit_ip"); v != "" {
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid exit_ip %q", v)
+		}
+		f.ExitIP = addr.String()
+	}
+	if v := q.Get("https"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid https %q", v)
+		}
+		f.SupportsHTTPS = &b
+	}
+	if v := q.Get("ipv6"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid ipv6 %q", v)
+		}
+		f.SupportsIPv6 = &b
+	}
+	if v := q.Get("ip_version"); v != "" {
+		if v != "4" && v != "6" {
+			return f, fmt.Errorf("invalid ip_version %q", v)
+		}
+		f.IPVersion, _ = strconv.Atoi(v)
+	}
+	if v := q.Get("include_tampered"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid include_tampered %q", v)
+		}
+		f.IncludeTampered = b
+	}
+	if v := q.Get("distinct_exit"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid distinct_exit %q", v)
+		}
+		f.DistinctExit = b
+	}
+	if v := q.Get("asn"); v != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(v), "AS"))
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("invalid asn %q", v)
+		}
+		f.ASN = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("invalid limit %q", v)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
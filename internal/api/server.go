@@ -0,0 +1,121 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"proxypool/internal/agent"
+	"proxypool/internal/storage"
+)
+
+// Config controls the HTTP API.
+type Config struct {
+	// Tokens identify authorised consumers. Only they get proxies that need
+	// credentials, and the credentials themselves.
+	Tokens []string
+
+	// AgentTokens identify checker agents (see package agent). Without any,
+	// the agent API refuses every request.
+	AgentTokens []string
+
+	// RegionInterval is how long a result from an agent's region stays
+	// fresh before the proxy is handed out there again. Defaults to 15m.
+	RegionInterval time.Duration
+
+	// Admin, if set, is controlled through /admin/ by holders of
+	// AdminTokens.
+	Admin       Controller
+	AdminTokens []string
+}
+
+// Server serves the proxy pool over HTTP.
+type Server struct {
+	repo storage.ProxyRepository
+	cfg  Config
+	mux  *http.ServeMux
+}
+
+func NewServer(repo storage.ProxyRepository, cfg Config) *Server {
+	if cfg.RegionInterval <= 0 {
+		cfg.RegionInterval = 15 * time.Minute
+	}
+	s := &Server{
+		repo: repo,
+		cfg:  cfg,
+		mux:  http.NewServeMux(),
+	}
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /proxies", s.handleListProxies)
+	s.mux.HandleFunc("GET /stats/failures", s.handleFailureStats)
+	s.mux.HandleFunc("POST "+agent.JobsPath, s.handleAgentJobs)
+	s.mux.HandleFunc("POST "+agent.ResultsPath, s.handleAgentResults)
+	s.mux.Handle("GET /metrics", expvar.Handler())
+	if s.cfg.Admin != nil {
+		s.adminRoutes()
+	}
+}
+
+// Handler returns the root HTTP handler.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// authorized reports whether the request carries a valid consumer token,
+// either as "Authorization: Bearer <token>" or "?token=".
+func (s *Server) authorized(r *http.Request) bool {
+	return hasToken(r, s.cfg.Tokens)
+}
+
+// agentAuthorized reports whether the request carries a valid agent token.
+// Agents get credentials in their jobs, so only the header is accepted.
+func (s *Server) agentAuthorized(r *http.Request) bool {
+	return hasBearer(r, s.cfg.AgentTokens)
+}
+
+func hasToken(r *http.Request, tokens []string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	return validToken(token, tokens)
+}
+
+// hasBearer is like hasToken but ignores "?token=", which ends up in access
+// and proxy logs. Tokens with more power than reading the pool use it.
+func hasBearer(r *http.Request, tokens []string) bool {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return validToken(token, tokens)
+}
+
+func validToken(token string, tokens []string) bool {
+	if token == "" {
+		return false
+	}
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxStatsWindow bounds how far back /stats/failures looks; older history is
+// pruned anyway.
+const maxStatsWindow = 30 * 24 * time.Hour
+
+// handleFailureStats serves GET /stats/failures: failed checks by class over
+// a window (?window=1h, default 24h) and dead proxies by the class of their
+// latest failure.
+func (s *Server) handleFailureStats(w http.ResponseWriter, r *http.Request) {
+	window := 24 * time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxStatsWindow {
+			writeError(w, http.StatusBadRequest, "invalid window")
+			return
+		}
+		window = d
+	}
+
+	stats, err := s.repo.FailureStats(r.Context(), time.Now().Add(-window))
+	if err != nil {
+		slog.Error("Failure stats failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
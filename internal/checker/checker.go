@@ -2,11 +2,15 @@ package checker
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"net/http"
+	"net"
 	"net/url"
+	"slices"
+	"sync"
 	"time"
 
+	"proxypool/internal/ipfilter"
 	"proxypool/internal/model"
 )
 
@@ -14,12 +18,68 @@ type CheckResult struct {
 	Alive     bool
 	LatencyMS int
 	Country   string // Placeholder for GeoIP
-	Anonymity string // Placeholder for header analysis
+	Anonymity string // Set by the anonymity validator
+	ExitIP    string // Address the judge saw, if a judge is configured
+	HTTPS     *bool  // Whether the proxy can tunnel TLS; nil if not tested
+	IPv6      *bool  // Whether the proxy can reach IPv6 targets; nil if not tested
+
+	// ThroughputKBps is the measured download rate; 0 if not measured.
+	ThroughputKBps int
+
+	// Tampered reports whether the proxy modified content or forged
+	// certificates; nil if not tested. TamperReason says how.
+	Tampered     *bool
+	TamperReason string
+
+	// FailedValidator names the validator that failed the check, if any,
+	// and Failure classifies why (one of the Failure* constants).
+	FailedValidator string
+	Failure         string
+
+	// Timings breaks down the first request through the proxy. It is kept
+	// for failed checks too, as far as the request got.
+	Timings model.Timings
+
+	// Attempts is how many times the pipeline ran (1 unless retried).
+	Attempts int
 }
 
 type Checker struct {
 	TargetURL string
 	Timeout   time.Duration
+
+	// JudgeURL, if set, is fetched through live proxies to learn their exit IP.
+	// Only used by the default pipeline.
+	JudgeURL string
+
+	// HTTPSURL, if set, is requested through live proxies to learn whether
+	// they can tunnel TLS. Only used by the default pipeline.
+	HTTPSURL string
+
+	// IPv6URL, if set, is an IPv6-only URL requested through live proxies to
+	// learn whether they can reach IPv6 targets. Only used by the default
+	// pipeline.
+	IPv6URL string
+
+	// TLSConfig is used for TLS connections made through the proxy.
+	// Nil means the system defaults.
+	TLSConfig *tls.Config
+
+	// Guard, if set, refuses to dial private/reserved proxy addresses so
+	// scraped entries can't make us probe internal hosts.
+	Guard *ipfilter.Filter
+
+	// Pipeline is the ordered list of validators run for every proxy.
+	// If empty, a HEAD to TargetURL is used, followed by optional HTTPS,
+	// IPv6 and exit IP checks when HTTPSURL, IPv6URL and JudgeURL are set.
+	Pipeline []Validator
+
+	// Retry re-runs checks that failed for reasons that may be transient.
+	// The zero value doesn't retry.
+	Retry Retry
+
+	defaultOnce     sync.Once
+	defaultPipeline []Validator
 }
 
 func NewChecker(targetURL string, timeout time.Duration) *Checker {
@@ -29,61 +89,163 @@ func NewChecker(targetURL string, timeout time.Duration) *Checker {
 	}
 }
 
-// Check validates the proxy by attempting to make a request to the target URL.
-func (c *Checker) Check(ctx context.Context, p *model.Proxy) (*CheckResult, error) {
-	// Construct Proxy URL
-	// Assume HTTP for simplicity if not set, or handle SOCKS later
-	// For this task, we assume HTTP/HTTPS proxies.
-	proxyStr := fmt.Sprintf("http://%s:%d", p.IP, p.Port)
-	if p.Protocol != "" {
-		proxyStr = fmt.Sprintf("%s://%s:%d", p.Protocol, p.IP, p.Port)
+// Hosts returns the hosts the checker's pipeline (and retries) send
+// requests to.
+func (c *Checker) Hosts() []string {
+	hosts := pipelineHosts(c.pipeline())
+	for _, t := range c.Retry.Targets {
+		hosts = appendHost(hosts, t)
 	}
+	return hosts
+}
 
-	proxyURL, err := url.Parse(proxyStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid proxy url: %w", err)
+// AttemptHosts returns the hosts the given attempt of a check (0 is the
+// first) sends requests to.
+func (c *Checker) AttemptHosts(attempt int) []string {
+	return pipelineHosts(retarget(c.pipeline(), c.TargetURL, c.Retry.target(attempt, c.TargetURL)))
+}
+
+// pipelineHosts returns the hosts the validators send requests to.
+func pipelineHosts(pipeline []Validator) []string {
+	var hosts []string
+	for _, v := range pipeline {
+		if o, ok := v.(optional); ok {
+			v = o.Validator
+		}
+		switch v := v.(type) {
+		case *HandshakeValidator:
+			hosts = appendHost(hosts, v.TargetURL)
+		case *HTTPValidator:
+			hosts = appendHost(hosts, v.URL)
+		case *HTTPSValidator:
+			hosts = appendHost(hosts, v.URL)
+		case *JudgeValidator:
+			hosts = appendHost(hosts, v.URL)
+		case *IntegrityValidator:
+			hosts = appendHost(hosts, v.PayloadURL)
+			hosts = appendHost(hosts, v.TLSURL)
+		case *ThroughputValidator:
+			hosts = appendHost(hosts, v.URL)
+		case *IPv6Validator:
+			hosts = appendHost(hosts, v.URL)
+		}
 	}
+	return hosts
+}
 
-	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
-		// Disable KeepAlives for checkers to save resources
-		DisableKeepAlives: true,
+// appendHost adds rawURL's host to hosts unless it is already there.
+func appendHost(hosts []string, rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || slices.Contains(hosts, u.Hostname()) {
+		return hosts
 	}
+	return append(hosts, u.Hostname())
+}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   c.Timeout,
+func (c *Checker) pipeline() []Validator {
+	if len(c.Pipeline) > 0 {
+		return c.Pipeline
 	}
+	c.defaultOnce.Do(func() {
+		c.defaultPipeline = []Validator{&HTTPValidator{URL: c.TargetURL, Method: "HEAD"}}
+		// The proxy works either way; we just may not know if it tunnels TLS
+		// or where it comes out.
+		if c.HTTPSURL != "" {
+			c.defaultPipeline = append(c.defaultPipeline, Optional(&HTTPSValidator{URL: c.HTTPSURL}))
+		}
+		if c.IPv6URL != "" {
+			c.defaultPipeline = append(c.defaultPipeline, Optional(&IPv6Validator{URL: c.IPv6URL}))
+		}
+		if c.JudgeURL != "" {
+			c.defaultPipeline = append(c.defaultPipeline, Optional(&JudgeValidator{URL: c.JudgeURL}))
+		}
+	})
+	return c.defaultPipeline
+}
 
-	start := time.Now()
-	
-	// Create a new context with timeout for the request
-	reqCtx, cancel := context.WithTimeout(ctx, c.Timeout)
-	defer cancel()
+// Check runs the pipeline against the proxy. Validators run in order and the
+// first failure stops the pipeline; the proxy is alive only if all pass.
+// A failed check is reported as Alive: false, not as an error.
+//
+// Failures that may be transient are retried as configured by c.Retry, each
+// attempt with a fresh connection and its own timeout. The result is that of
+// the last attempt.
+func (c *Checker) Check(ctx context.Context, p *model.Proxy) (*CheckResult, error) {
+	pipeline := c.pipeline()
+	for attempt := 0; ; attempt++ {
+		target := c.Retry.target(attempt, c.TargetURL)
+		res, err := c.check(ctx, p, retarget(pipeline, c.TargetURL, target))
+		if err != nil {
+			return nil, err
+		}
+		res.Attempts = attempt + 1
 
-	req, err := http.NewRequestWithContext(reqCtx, "HEAD", c.TargetURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("bad request: %w", err)
+		if res.Alive || attempt >= c.Retry.Attempts || !Retryable(res.Failure) {
+			return res, nil
+		}
+		if err := sleep(ctx, c.Retry.Delay); err != nil {
+			return res, nil
+		}
+		if c.Retry.Admit != nil && !c.Retry.Admit(ctx, p, c.AttemptHosts(attempt+1)) {
+			return res, nil
+		}
 	}
+}
 
-	resp, err := client.Do(req)
+// check runs pipeline once.
+func (c *Checker) check(ctx context.Context, p *model.Proxy, pipeline []Validator) (*CheckResult, error) {
+	probe, err := c.newProbe(p)
 	if err != nil {
-		// Connection failed
-		return &CheckResult{Alive: false}, nil 
-		// Note: We return Alive: false instead of error to indicate "checked but failed"
+		return nil, err
+	}
+
+	// The pipeline shares one timeout, except for validators with their own.
+	budget := c.Timeout
+	start := time.Now()
+	res := probe.Result
+	for _, v := range pipeline {
+		timeout, own := ownTimeout(v)
+		if !own {
+			timeout = budget
+		}
+		began := time.Now()
+		vctx, cancel := context.WithTimeout(ctx, timeout)
+		err := v.Validate(vctx, probe)
+		cancel()
+		if !own {
+			budget -= time.Since(began)
+		}
+		if err != nil {
+			res.FailedValidator = v.Name()
+			res.Failure = Classify(err, probe.connected.Load())
+			return res, nil
+		}
 	}
-	defer resp.Body.Close()
 
-	Latency := time.Since(start).Milliseconds()
+	res.Alive = true
+	if res.LatencyMS == 0 {
+		res.LatencyMS = max(1, int(time.Since(start).Milliseconds()))
+	}
+	return res, nil
+}
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-		return &CheckResult{
-			Alive:     true,
-			LatencyMS: int(Latency),
-			// Anonymity detection requires inspecting returned headers (e.g. from httpbin), HEAD doesn't show body.
-			// For basic liveness, this is enough.
-		}, nil
+func (c *Checker) newProbe(p *model.Proxy) (*Probe, error) {
+	// Construct Proxy URL (defaults to HTTP). Credentials, if any, are sent as
+	// Proxy-Authorization Basic for HTTP proxies and as SOCKS5 user/pass.
+	proxyURL, err := url.Parse(p.AuthURL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
 	}
 
-	return &CheckResult{Alive: false}, nil
+	return &Probe{
+		Proxy:  p,
+		Result: &CheckResult{},
+		dialer: &net.Dialer{
+			Timeout: c.Timeout,
+			Control: c.Guard.Control,
+		},
+		proxyURL:  proxyURL,
+		timeout:   c.Timeout,
+		tlsConfig: c.TLSConfig,
+	}, nil
 }
@@ -2,14 +2,17 @@ package checker
 
 import (
 	"context"
+	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"proxypool/internal/ipfilter"
 	"proxypool/internal/model"
 )
 
@@ -46,7 +49,7 @@ func TestChecker_Check(t *testing.T) {
 	}
 
 	c := NewChecker(targetServer.URL, 2*time.Second)
-	
+
 	result, err := c.Check(context.Background(), p)
 	if err != nil {
 		t.Fatalf("Check returned error: %v", err)
@@ -70,7 +73,7 @@ func TestChecker_Check_Dead(t *testing.T) {
 	}
 
 	c := NewChecker("http://google.com", 100*time.Millisecond) // Short timeout
-	
+
 	result, err := c.Check(context.Background(), p)
 	if err != nil {
 		t.Fatalf("Unexpected error structure (should return Alive:false, not err): %v", err)
@@ -80,3 +83,90 @@ func TestChecker_Check_Dead(t *testing.T) {
 		t.Errorf("Expected proxy to be dead")
 	}
 }
+
+func TestChecker_Check_Auth(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	// Proxy that demands Basic credentials
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:p@ss")) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	port, _ := strconv.Atoi(proxyURL.Port())
+
+	c := NewChecker(targetServer.URL, 2*time.Second)
+
+	p := &model.Proxy{IP: proxyURL.Hostname(), Port: port, Protocol: "http"}
+	result, err := c.Check(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if result.Alive {
+		t.Errorf("Expected proxy without credentials to fail")
+	}
+
+	p.Username, p.Password = "user", "p@ss"
+	result, err = c.Check(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if !result.Alive {
+		t.Errorf("Expected proxy with credentials to be alive")
+	}
+}
+
+func TestChecker_Check_Guard(t *testing.T) {
+	// A live proxy on loopback must not be dialled when guarded.
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Guarded checker reached the proxy")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	port, _ := strconv.Atoi(proxyURL.Port())
+
+	c := NewChecker("http://example.com", 2*time.Second)
+	c.Guard = ipfilter.New(nil)
+
+	result, err := c.Check(context.Background(), &model.Proxy{IP: proxyURL.Hostname(), Port: port, Protocol: "http"})
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if result.Alive {
+		t.Errorf("Expected guarded proxy to be reported dead")
+	}
+}
+
+func TestChecker_Hosts(t *testing.T) {
+	c := NewChecker("http://google.com", time.Second)
+	c.HTTPSURL = "https://www.google.com"
+	c.JudgeURL = "http://judge.test:8080/judge"
+	c.Retry.Targets = []string{"http://example.com", "http://google.com/generate_204"}
+
+	want := []string{"google.com", "www.google.com", "judge.test", "example.com"}
+	if got := c.Hosts(); !slices.Equal(got, want) {
+		t.Errorf("Hosts() = %v, want %v", got, want)
+	}
+
+	// Each attempt only hits its own target.
+	attempts := [][]string{
+		{"google.com", "www.google.com", "judge.test"},
+		{"example.com", "www.google.com", "judge.test"},
+		{"google.com", "www.google.com", "judge.test"},
+	}
+	for attempt, want := range attempts {
+		if got := c.AttemptHosts(attempt); !slices.Equal(got, want) {
+			t.Errorf("AttemptHosts(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
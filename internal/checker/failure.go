@@ -0,0 +1,130 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+
+	"proxypool/internal/ipfilter"
+)
+
+// Failure classes, as stored and reported.
+const (
+	FailureRefused          = "refused"           // TCP connection to the proxy refused
+	FailureUnreachable      = "unreachable"       // No route to the proxy
+	FailureDenied           = "denied"            // Address blocked by the dial guard
+	FailureDialTimeout      = "dial_timeout"      // Timed out connecting to the proxy
+	FailureResponseTimeout  = "response_timeout"  // Connected, but the response never came
+	FailureAuthRequired     = "auth_required"     // Proxy wants (other) credentials
+	FailureBadGateway       = "bad_gateway"       // Proxy couldn't reach the target
+	FailureTLS              = "tls_error"         // TLS handshake or certificate failure
+	FailureStatus           = "unexpected_status" // Target answered with a status we don't accept
+	FailureProtocolMismatch = "protocol_mismatch" // Proxy doesn't speak its listed protocol
+	FailureContentMismatch  = "content_mismatch"  // Body didn't match what was expected
+	FailureOther            = "other"
+)
+
+// ErrProxyAuth means the proxy rejected our credentials or wanted some.
+var ErrProxyAuth = errors.New("proxy authentication required")
+
+// ErrContentMismatch means a response body didn't match.
+var ErrContentMismatch = errors.New("content mismatch")
+
+// StatusError is an unaccepted HTTP status from the proxy or target.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d", e.Code)
+}
+
+// Classify maps a check error to a failure class. connected says whether a
+// TCP connection to the proxy was made, which tells a dial timeout from a
+// response timeout.
+func Classify(err error, connected bool) string {
+	if err == nil {
+		return ""
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return classifyStatus(statusErr.Code)
+	}
+	if code, ok := connectStatus(err); ok {
+		return classifyStatus(code)
+	}
+
+	var (
+		certErr     *tls.CertificateVerificationError
+		unknownAuth x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		netErr      net.Error
+	)
+	switch {
+	case errors.Is(err, ErrProxyAuth):
+		return FailureAuthRequired
+	case errors.Is(err, ErrProtocolMismatch):
+		return FailureProtocolMismatch
+	case errors.Is(err, ErrContentMismatch):
+		return FailureContentMismatch
+	case errors.Is(err, ipfilter.ErrDenied):
+		return FailureDenied
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureRefused
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return FailureUnreachable
+	case errors.As(err, &certErr), errors.As(err, &unknownAuth), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidCert), errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return FailureTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		if connected {
+			return FailureResponseTimeout
+		}
+		return FailureDialTimeout
+	}
+	return FailureOther
+}
+
+// DialFailure classifies an error from a bare connect to the proxy.
+func DialFailure(err error) string {
+	return Classify(err, false)
+}
+
+func classifyStatus(code int) string {
+	switch code {
+	case http.StatusProxyAuthRequired:
+		return FailureAuthRequired
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return FailureBadGateway
+	}
+	return FailureStatus
+}
+
+// connectStatus recognises a refused CONNECT. net/http reports those as an
+// error holding just the status text ("Bad Gateway").
+func connectStatus(err error) (int, bool) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	text := err.Error()
+	for code := 400; code < 600; code++ {
+		if t := http.StatusText(code); t != "" && strings.EqualFold(text, t) {
+			return code, true
+		}
+	}
+	return 0, false
+}
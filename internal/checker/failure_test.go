@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"proxypool/internal/ipfilter"
+	"proxypool/internal/model"
+)
+
+func TestClassify(t *testing.T) {
+	opErr := func(err error) error { return &net.OpError{Op: "dial", Net: "tcp", Err: err} }
+
+	tests := []struct {
+		err       error
+		connected bool
+		want      string
+	}{
+		{nil, false, ""},
+		{opErr(syscall.ECONNREFUSED), false, FailureRefused},
+		{opErr(syscall.EHOSTUNREACH), false, FailureUnreachable},
+		{fmt.Errorf("dial: %w", ipfilter.ErrDenied), false, FailureDenied},
+		{context.DeadlineExceeded, false, FailureDialTimeout},
+		{&url.Error{Op: "Get", URL: "http://x", Err: os.ErrDeadlineExceeded}, true, FailureResponseTimeout},
+		{&StatusError{Code: 407}, true, FailureAuthRequired},
+		{&StatusError{Code: 502}, true, FailureBadGateway},
+		{&StatusError{Code: 403}, true, FailureStatus},
+		{&url.Error{Op: "Head", URL: "https://x", Err: errors.New("Bad Gateway")}, true, FailureBadGateway},
+		{&url.Error{Op: "Head", URL: "https://x", Err: errors.New("Proxy Authentication Required")}, true, FailureAuthRequired},
+		{&url.Error{Op: "Head", URL: "https://x", Err: x509.UnknownAuthorityError{}}, true, FailureTLS},
+		{fmt.Errorf("%w: socks5 version 72", ErrProtocolMismatch), true, FailureProtocolMismatch},
+		{fmt.Errorf("%w: missing", ErrContentMismatch), true, FailureContentMismatch},
+		{errors.New("something odd"), true, FailureOther},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.err, tt.connected); got != tt.want {
+			t.Errorf("Classify(%v, %v) = %q, want %q", tt.err, tt.connected, got, tt.want)
+		}
+	}
+}
+
+func TestChecker_Check_Failure(t *testing.T) {
+	statusProxy := func(code int) *model.Proxy {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+		t.Cleanup(ts.Close)
+		u, _ := url.Parse(ts.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return &model.Proxy{IP: u.Hostname(), Port: port, Protocol: "http"}
+	}
+
+	tests := []struct {
+		name   string
+		proxy  *model.Proxy
+		target string
+		want   string
+	}{
+		{"auth", statusProxy(http.StatusProxyAuthRequired), "http://target.test/", FailureAuthRequired},
+		{"connect refused by proxy", statusProxy(http.StatusBadGateway), "https://target.test/", FailureBadGateway},
+		{"closed port", &model.Proxy{IP: "127.0.0.1", Port: 1, Protocol: "http"}, "http://target.test/", FailureRefused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(tt.target, 2*time.Second)
+			result, err := c.Check(context.Background(), tt.proxy)
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if result.Alive || result.Failure != tt.want {
+				t.Errorf("Failure = %q, want %q (%+v)", result.Failure, tt.want, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxPayloadBody limits how much of the integrity payload we read.
+const maxPayloadBody = 4 << 20
+
+// IntegrityValidator detects proxies that tamper with traffic. It fetches a
+// payload with a known hash (the judge's /payload) and compares the body,
+// and, if TLSURL is set, inspects the certificate presented through the
+// tunnel for forgery.
+//
+// Tampering doesn't fail the check: the proxy is flagged in
+// CheckResult.Tampered and the API hides it by default. Errors mean the
+// integrity couldn't be determined.
+type IntegrityValidator struct {
+	PayloadURL  string
+	PayloadHash string // hex SHA-256
+
+	// TLSURL is an HTTPS URL whose certificate chain must verify.
+	TLSURL string
+	// TLSFingerprint, if set, pins the hex SHA-256 of TLSURL's leaf certificate.
+	TLSFingerprint string
+	// RootCAs verifies TLSURL's chain; nil means the system roots.
+	RootCAs *x509.CertPool
+}
+
+func (v *IntegrityValidator) Name() string {
+	return "integrity"
+}
+
+func (v *IntegrityValidator) Validate(ctx context.Context, probe *Probe) error {
+	reason, err := v.checkPayload(ctx, probe)
+	if err != nil {
+		return err
+	}
+	if reason == "" && v.TLSURL != "" {
+		if reason, err = v.checkTLS(ctx, probe); err != nil {
+			return err
+		}
+	}
+
+	tampered := reason != ""
+	probe.Result.Tampered = &tampered
+	probe.Result.TamperReason = reason
+	return nil
+}
+
+// checkPayload returns a non-empty reason if the payload came back modified.
+func (v *IntegrityValidator) checkPayload(ctx context.Context, probe *Probe) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", v.PayloadURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("bad payload request: %w", err)
+	}
+
+	resp, err := probe.Client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("payload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("payload returned status %d", resp.StatusCode)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(resp.Body, maxPayloadBody)); err != nil {
+		return "", fmt.Errorf("read payload: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, v.PayloadHash) {
+		return "payload modified", nil
+	}
+	return "", nil
+}
+
+// checkTLS returns a non-empty reason if the certificate seen through the
+// tunnel doesn't verify or doesn't match the pin. The handshake itself skips
+// verification so that a forged certificate is observed rather than just
+// failing the connection.
+func (v *IntegrityValidator) checkTLS(ctx context.Context, probe *Probe) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", v.TLSURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("bad tls request: %w", err)
+	}
+
+	client := probe.ClientWithTLS(&tls.Config{InsecureSkipVerify: true})
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("tls request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return "", errors.New("tls request: no certificates")
+	}
+	certs := resp.TLS.PeerCertificates
+	leaf := certs[0]
+
+	if v.TLSFingerprint != "" {
+		sum := sha256.Sum256(leaf.Raw)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), v.TLSFingerprint) {
+			return "certificate fingerprint mismatch", nil
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       req.URL.Hostname(),
+		Roots:         v.RootCAs,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return "forged certificate: " + err.Error(), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,121 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"proxypool/internal/judge"
+	"proxypool/internal/model"
+)
+
+// forwardProxy starts an HTTP proxy that forwards plain requests (appending
+// inject to every body) and tunnels CONNECT.
+func forwardProxy(t *testing.T, inject string) *model.Proxy {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			upstream, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			go func() {
+				io.Copy(upstream, conn)
+				upstream.Close()
+			}()
+			io.Copy(conn, upstream)
+			conn.Close()
+			return
+		}
+
+		resp, err := http.Get(r.URL.String())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body)
+		io.WriteString(w, inject)
+	}))
+	t.Cleanup(ts.Close)
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &model.Proxy{IP: u.Hostname(), Port: port, Protocol: "http"}
+}
+
+func TestIntegrityValidator(t *testing.T) {
+	judgeServer := httptest.NewServer(judge.Handler())
+	defer judgeServer.Close()
+
+	tlsTarget := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsTarget.Close()
+	roots := tlsTarget.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	leaf := sha256.Sum256(tlsTarget.Certificate().Raw)
+
+	tests := []struct {
+		name       string
+		inject     string
+		validator  IntegrityValidator
+		wantReason string
+	}{
+		{
+			name:      "clean",
+			validator: IntegrityValidator{TLSURL: tlsTarget.URL, RootCAs: roots, TLSFingerprint: hex.EncodeToString(leaf[:])},
+		},
+		{
+			name:       "injected",
+			inject:     "<script>ads()</script>",
+			wantReason: "payload modified",
+		},
+		{
+			// The test server's certificate isn't trusted by the system roots,
+			// which is exactly what a forged one looks like.
+			name:       "untrusted certificate",
+			validator:  IntegrityValidator{TLSURL: tlsTarget.URL},
+			wantReason: "forged certificate",
+		},
+		{
+			name:       "pin mismatch",
+			validator:  IntegrityValidator{TLSURL: tlsTarget.URL, RootCAs: roots, TLSFingerprint: "00"},
+			wantReason: "certificate fingerprint mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.validator
+			v.PayloadURL = judgeServer.URL + "/payload"
+			v.PayloadHash = judge.PayloadHash
+
+			c := NewChecker(judgeServer.URL, 2*time.Second)
+			c.Pipeline = []Validator{&v}
+
+			result, err := c.Check(context.Background(), forwardProxy(t, tt.inject))
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if !result.Alive {
+				t.Fatalf("Tampering should be flagged, not fail the check: %+v", result)
+			}
+			if result.Tampered == nil || *result.Tampered != (tt.wantReason != "") {
+				t.Fatalf("Tampered = %v, want %v", result.Tampered, tt.wantReason != "")
+			}
+			if !strings.HasPrefix(result.TamperReason, tt.wantReason) {
+				t.Errorf("TamperReason = %q, want prefix %q", result.TamperReason, tt.wantReason)
+			}
+		})
+	}
+}
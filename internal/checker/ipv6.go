@@ -0,0 +1,56 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// maxIPv6Body limits how much of the IPv6 test page we read.
+const maxIPv6Body = 4 << 10
+
+// IPv6Validator checks whether the proxy can reach IPv6 targets by fetching
+// an IPv6-only URL through it. If the page echoes the caller's address (as
+// api6.ipify.org does), that address must be IPv6 too, so a target that
+// quietly answers over IPv4 doesn't count. The outcome is recorded in
+// CheckResult.IPv6 even when the validator is optional.
+type IPv6Validator struct {
+	URL string
+}
+
+func (v *IPv6Validator) Name() string {
+	return "ipv6"
+}
+
+func (v *IPv6Validator) Validate(ctx context.Context, probe *Probe) error {
+	ok := false
+	defer func() { probe.Result.IPv6 = &ok }()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", v.URL, nil)
+	if err != nil {
+		return fmt.Errorf("bad request: %w", err)
+	}
+
+	resp, err := probe.Client().Do(req)
+	if err != nil {
+		return fmt.Errorf("ipv6 request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return &StatusError{Code: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIPv6Body))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(string(body))); err == nil && !addr.Unmap().Is6() {
+		return fmt.Errorf("ipv6 request came out over IPv4 (%s)", addr)
+	}
+	ok = true
+	return nil
+}
@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+func TestIPv6Validator(t *testing.T) {
+	tests := []struct {
+		name string
+		echo string // what the "IPv6-only" page reports as our address
+		want bool
+	}{
+		{"ipv6 exit", "2001:db8::1\n", true},
+		{"ipv4 exit", "203.0.113.7", false},
+		{"no echo", "<html>ok</html>", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.echo)
+			}))
+			defer target.Close()
+
+			c := NewChecker(target.URL, 2*time.Second)
+			c.Pipeline = []Validator{Optional(&IPv6Validator{URL: target.URL})}
+
+			result, err := c.Check(context.Background(), forwardProxy(t, ""))
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if !result.Alive {
+				t.Fatalf("Optional ipv6 validator failed the check: %+v", result)
+			}
+			if result.IPv6 == nil || *result.IPv6 != tt.want {
+				t.Errorf("IPv6 = %v, want %v", result.IPv6, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_IPv6Proxy(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	proxy.Listener = ln
+	proxy.Start()
+	defer proxy.Close()
+
+	u, _ := url.Parse(proxy.URL)
+	port, _ := strconv.Atoi(u.Port())
+	p := &model.Proxy{IP: "::1", Port: port, Protocol: "http"}
+	if want := "http://[::1]:" + u.Port(); p.URL() != want {
+		t.Fatalf("URL() = %q, want %q", p.URL(), want)
+	}
+
+	result, err := NewChecker("http://target.test/", 2*time.Second).Check(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if !result.Alive {
+		t.Errorf("Expected IPv6 proxy to be alive: %+v", result)
+	}
+}
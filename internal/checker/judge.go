@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// maxJudgeBody limits how much of a judge response we read.
+const maxJudgeBody = 64 << 10
+
+// JudgeValidator fetches a judge through the proxy to learn its exit IP and,
+// when the judge echoes request headers (as internal/judge does), its
+// anonymity level.
+type JudgeValidator struct {
+	URL string
+
+	once   sync.Once
+	realIP string // our own address as seen by the judge, "" if unknown
+}
+
+func (v *JudgeValidator) Name() string {
+	return "anonymity"
+}
+
+func (v *JudgeValidator) Validate(ctx context.Context, probe *Probe) error {
+	body, err := fetchJudge(ctx, probe.Client(), v.URL)
+	if err != nil {
+		return err
+	}
+	exitIP, err := ParseExitIP(body)
+	if err != nil {
+		return err
+	}
+	probe.Result.ExitIP = exitIP
+
+	var echo struct {
+		Headers map[string]string `json:"headers"`
+	}
+	json.Unmarshal(body, &echo)
+	probe.Result.Anonymity = classifyAnonymity(exitIP, echo.Headers, v.ownIP())
+	return nil
+}
+
+// ownIP asks the judge, without a proxy, for our own address. It is looked up
+// once; if that fails, anonymity is judged on proxy headers alone.
+func (v *JudgeValidator) ownIP() string {
+	v.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		body, err := fetchJudge(ctx, http.DefaultClient, v.URL)
+		if err == nil {
+			v.realIP, err = ParseExitIP(body)
+		}
+		if err != nil {
+			slog.Warn("Could not determine own IP; transparent proxies may be misclassified", "judge", v.URL, "error", err)
+		}
+	})
+	return v.realIP
+}
+
+// proxyHeaders are added by proxies that don't hide the fact they're proxies.
+var proxyHeaders = []string{
+	"Via",
+	"X-Forwarded-For",
+	"Forwarded",
+	"X-Real-Ip",
+	"Client-Ip",
+	"Proxy-Connection",
+	"X-Proxy-Id",
+}
+
+// classifyAnonymity derives the anonymity level from what the judge saw.
+// Headers are nil for judges that only report the IP, in which case only
+// transparency can be detected.
+func classifyAnonymity(exitIP string, headers map[string]string, realIP string) string {
+	if realIP != "" {
+		if exitIP == realIP {
+			return model.AnonymityTransparent
+		}
+		for _, v := range headers {
+			if strings.Contains(v, realIP) {
+				return model.AnonymityTransparent
+			}
+		}
+	}
+	if headers == nil {
+		return ""
+	}
+	for _, h := range proxyHeaders {
+		if _, ok := headers[h]; ok {
+			return model.AnonymityAnonymous
+		}
+	}
+	return model.AnonymityElite
+}
+
+func fetchJudge(ctx context.Context, client *http.Client, judgeURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", judgeURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bad judge request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("judge request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("judge returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJudgeBody))
+	if err != nil {
+		return nil, fmt.Errorf("read judge response: %w", err)
+	}
+	return body, nil
+}
+
+// ParseExitIP extracts the client IP from a judge response. It understands our
+// own judge and common public services: JSON with "ip" (ipify, our judge),
+// "origin" (httpbin, possibly "a, b") or "query" (ip-api), or a bare IP.
+func ParseExitIP(body []byte) (string, error) {
+	var fields struct {
+		IP     string `json:"ip"`
+		Origin string `json:"origin"`
+		Query  string `json:"query"`
+	}
+	candidate := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &fields); err == nil {
+		switch {
+		case fields.IP != "":
+			candidate = fields.IP
+		case fields.Origin != "":
+			// httpbin lists the forwarding chain; the first entry is the client.
+			candidate, _, _ = strings.Cut(fields.Origin, ",")
+		default:
+			candidate = fields.Query
+		}
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(candidate))
+	if err != nil {
+		return "", fmt.Errorf("no IP in judge response: %q", truncate(candidate, 64))
+	}
+	return addr.Unmap().String(), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,74 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+func TestParseExitIP(t *testing.T) {
+	tests := []struct {
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{body: `{"ip":"203.0.113.7","headers":{}}`, want: "203.0.113.7"},
+		{body: `{"origin": "198.51.100.1, 203.0.113.7"}`, want: "198.51.100.1"},
+		{body: `{"status":"success","query":"2001:db8::1"}`, want: "2001:db8::1"},
+		{body: "203.0.113.7\n", want: "203.0.113.7"},
+		{body: "::ffff:203.0.113.7", want: "203.0.113.7"},
+		{body: "<html>blocked</html>", wantErr: true},
+		{body: `{"ip":""}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExitIP([]byte(tt.body))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseExitIP(%q) expected error, got %q", tt.body, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseExitIP(%q) = %q, %v; want %q", tt.body, got, err, tt.want)
+		}
+	}
+}
+
+func TestChecker_Check_ExitIP(t *testing.T) {
+	const judgeURL = "http://judge.test/"
+
+	// Proxy that exits from a different address than the one we dial
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == judgeURL {
+			fmt.Fprint(w, `{"ip":"203.0.113.7"}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	port, _ := strconv.Atoi(proxyURL.Port())
+
+	c := NewChecker("http://target.test/", 2*time.Second)
+	c.JudgeURL = judgeURL
+
+	result, err := c.Check(context.Background(), &model.Proxy{IP: proxyURL.Hostname(), Port: port, Protocol: "http"})
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if !result.Alive {
+		t.Fatalf("Expected proxy to be alive")
+	}
+	if result.ExitIP != "203.0.113.7" {
+		t.Errorf("ExitIP = %q, want %q", result.ExitIP, "203.0.113.7")
+	}
+}
@@ -0,0 +1,212 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// Validator is one step of the check pipeline.
+type Validator interface {
+	// Name identifies the validator in configuration and results.
+	Name() string
+
+	// Validate checks one aspect of the proxy and records what it learns in
+	// probe.Result. Returning an error fails the check and stops the pipeline.
+	Validate(ctx context.Context, probe *Probe) error
+}
+
+// Probe is the state shared by the validators checking one proxy.
+type Probe struct {
+	Proxy  *model.Proxy
+	Result *CheckResult
+
+	dialer    *net.Dialer
+	connected atomic.Bool // a TCP connection to the proxy succeeded
+	proxyURL  *url.URL
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	client    *http.Client
+}
+
+// Client returns an HTTP client that sends requests through the proxy.
+func (p *Probe) Client() *http.Client {
+	if p.client == nil {
+		p.client = p.ClientWithTLS(p.tlsConfig)
+	}
+	return p.client
+}
+
+// ClientWithTLS is like Client but uses cfg for TLS connections made through
+// the proxy.
+func (p *Probe) ClientWithTLS(cfg *tls.Config) *http.Client {
+	transport := &http.Transport{
+		Proxy:           http.ProxyURL(p.proxyURL),
+		DialContext:     p.dial,
+		TLSClientConfig: cfg,
+		// Disable KeepAlives for checkers to save resources
+		DisableKeepAlives: true,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   p.timeout,
+	}
+}
+
+// UntimedClient is like Client but without the check timeout, for
+// validators that run under a deadline of their own.
+func (p *Probe) UntimedClient() *http.Client {
+	client := *p.Client()
+	client.Timeout = 0
+	return &client
+}
+
+// Dial opens a raw TCP connection to the proxy itself.
+func (p *Probe) Dial(ctx context.Context) (net.Conn, error) {
+	return p.dial(ctx, "tcp", p.Proxy.Address())
+}
+
+func (p *Probe) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := p.dialer.DialContext(ctx, network, address)
+	if err == nil {
+		p.connected.Store(true)
+	}
+	return conn, err
+}
+
+// timed is implemented by validators that run under their own deadline
+// rather than the check timeout, which their time doesn't count against.
+type timed interface {
+	ownTimeout() time.Duration
+}
+
+// ownTimeout returns v's own deadline, if it has one.
+func ownTimeout(v Validator) (time.Duration, bool) {
+	if o, ok := v.(optional); ok {
+		v = o.Validator
+	}
+	if t, ok := v.(timed); ok {
+		return t.ownTimeout(), true
+	}
+	return 0, false
+}
+
+// optional wraps a validator whose failure is logged but doesn't fail the check.
+type optional struct {
+	Validator
+}
+
+// Optional makes v informational: its errors don't stop the pipeline.
+func Optional(v Validator) Validator {
+	return optional{v}
+}
+
+func (o optional) Validate(ctx context.Context, probe *Probe) error {
+	if err := o.Validator.Validate(ctx, probe); err != nil {
+		slog.Debug("Optional validator failed", "validator", o.Name(), "proxy", probe.Proxy.Address(), "error", err)
+	}
+	return nil
+}
+
+// PipelineConfig holds the settings validators are built from.
+type PipelineConfig struct {
+	TargetURL    string // http, handshake
+	HTTPSURL     string // https
+	JudgeURL     string // anonymity
+	ContentURL   string // content
+	ContentMatch string // content: substring the body must contain
+
+	PayloadURL     string         // integrity: page with a known hash, e.g. the judge's /payload
+	PayloadHash    string         // integrity: hex SHA-256 of the payload
+	TLSURL         string         // integrity: HTTPS URL whose certificate must verify (optional)
+	TLSFingerprint string         // integrity: hex SHA-256 pin of TLSURL's leaf certificate (optional)
+	RootCAs        *x509.CertPool // integrity: roots for TLSURL; nil means the system roots
+
+	ThroughputURL     string        // throughput: sized payload, e.g. the judge's /bytes?n=5242880
+	MinKBps           int           // throughput: fail proxies slower than this (optional)
+	ThroughputTimeout time.Duration // throughput: bounds the download (optional, default 10s)
+
+	IPv6URL string // ipv6: IPv6-only URL, e.g. http://api6.ipify.org
+}
+
+// NewPipeline builds a pipeline from a spec such as
+// "tcp,handshake,http,https?,anonymity?". A trailing "?" marks a validator
+// as optional. Available validators: tcp, handshake, http, https, content,
+// anonymity, integrity, throughput, ipv6.
+func NewPipeline(spec string, cfg PipelineConfig) ([]Validator, error) {
+	var pipeline []Validator
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		name, isOptional := strings.CutSuffix(name, "?")
+
+		var v Validator
+		switch name {
+		case "tcp":
+			v = &TCPValidator{}
+		case "handshake":
+			v = &HandshakeValidator{TargetURL: cfg.TargetURL}
+		case "http":
+			v = &HTTPValidator{URL: cfg.TargetURL}
+		case "https":
+			if cfg.HTTPSURL == "" {
+				return nil, fmt.Errorf("https validator needs a URL")
+			}
+			v = &HTTPSValidator{URL: cfg.HTTPSURL}
+		case "content":
+			if cfg.ContentURL == "" || cfg.ContentMatch == "" {
+				return nil, fmt.Errorf("content validator needs a URL and a match string")
+			}
+			v = &HTTPValidator{URL: cfg.ContentURL, Contains: cfg.ContentMatch, name: "content"}
+		case "anonymity":
+			if cfg.JudgeURL == "" {
+				return nil, fmt.Errorf("anonymity validator needs a judge URL")
+			}
+			v = &JudgeValidator{URL: cfg.JudgeURL}
+		case "integrity":
+			if cfg.PayloadURL == "" || cfg.PayloadHash == "" {
+				return nil, fmt.Errorf("integrity validator needs a payload URL and hash")
+			}
+			v = &IntegrityValidator{
+				PayloadURL:     cfg.PayloadURL,
+				PayloadHash:    cfg.PayloadHash,
+				TLSURL:         cfg.TLSURL,
+				TLSFingerprint: cfg.TLSFingerprint,
+				RootCAs:        cfg.RootCAs,
+			}
+		case "throughput":
+			if cfg.ThroughputURL == "" {
+				return nil, fmt.Errorf("throughput validator needs a URL")
+			}
+			v = &ThroughputValidator{URL: cfg.ThroughputURL, MinKBps: cfg.MinKBps, Timeout: cfg.ThroughputTimeout}
+		case "ipv6":
+			if cfg.IPv6URL == "" {
+				return nil, fmt.Errorf("ipv6 validator needs a URL")
+			}
+			v = &IPv6Validator{URL: cfg.IPv6URL}
+		default:
+			return nil, fmt.Errorf("unknown validator %q", name)
+		}
+
+		if isOptional {
+			v = Optional(v)
+		}
+		pipeline = append(pipeline, v)
+	}
+	if len(pipeline) == 0 {
+		return nil, fmt.Errorf("empty pipeline")
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,216 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// testProxy starts an HTTP proxy that answers every request with body.
+func testProxy(t *testing.T, body string) *model.Proxy {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(ts.Close)
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &model.Proxy{IP: u.Hostname(), Port: port, Protocol: "http"}
+}
+
+// countingValidator records whether it ran.
+type countingValidator struct {
+	name string
+	err  error
+	runs int
+}
+
+func (v *countingValidator) Name() string { return v.name }
+
+func (v *countingValidator) Validate(ctx context.Context, probe *Probe) error {
+	v.runs++
+	return v.err
+}
+
+func TestChecker_Pipeline(t *testing.T) {
+	p := testProxy(t, "hello world")
+
+	t.Run("content match", func(t *testing.T) {
+		c := NewChecker("http://target.test/", 2*time.Second)
+		var err error
+		c.Pipeline, err = NewPipeline("tcp, handshake, http, content", PipelineConfig{
+			TargetURL:    "http://target.test/",
+			ContentURL:   "http://target.test/page",
+			ContentMatch: "hello",
+		})
+		if err != nil {
+			t.Fatalf("NewPipeline failed: %v", err)
+		}
+
+		result, err := c.Check(context.Background(), p)
+		if err != nil {
+			t.Fatalf("Check returned error: %v", err)
+		}
+		if !result.Alive || result.FailedValidator != "" || result.LatencyMS <= 0 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("short circuit", func(t *testing.T) {
+		failing := &countingValidator{name: "first", err: errors.New("nope")}
+		after := &countingValidator{name: "second"}
+		c := NewChecker("http://target.test/", 2*time.Second)
+		c.Pipeline = []Validator{failing, after}
+
+		result, _ := c.Check(context.Background(), p)
+		if result.Alive || result.FailedValidator != "first" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if after.runs != 0 {
+			t.Errorf("Validator after a failure ran %d times", after.runs)
+		}
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		c := NewChecker("http://target.test/", 2*time.Second)
+		c.Pipeline = []Validator{
+			Optional(&HTTPValidator{URL: "http://target.test/", Contains: "missing"}),
+			&HTTPValidator{URL: "http://target.test/"},
+		}
+
+		result, _ := c.Check(context.Background(), p)
+		if !result.Alive {
+			t.Errorf("Optional failure should not fail the check: %+v", result)
+		}
+	})
+
+	t.Run("content mismatch", func(t *testing.T) {
+		c := NewChecker("http://target.test/", 2*time.Second)
+		c.Pipeline = []Validator{&HTTPValidator{URL: "http://target.test/", Contains: "captcha", name: "content"}}
+
+		result, _ := c.Check(context.Background(), p)
+		if result.Alive || result.FailedValidator != "content" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestHandshakeValidator_SOCKS5(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Minimal SOCKS5 server requiring user/pass.
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 3)
+				io.ReadFull(conn, buf)
+				conn.Write([]byte{0x05, 0x02})
+				buf = make([]byte, 64)
+				n, _ := conn.Read(buf)
+				status := byte(0x01)
+				if string(buf[2:2+buf[1]]) == "user" && n > 0 {
+					status = 0x00
+				}
+				conn.Write([]byte{0x01, status})
+			}()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	c := NewChecker("http://target.test/", 2*time.Second)
+	c.Pipeline = []Validator{&HandshakeValidator{TargetURL: "http://target.test/"}}
+
+	tests := []struct {
+		name  string
+		proxy *model.Proxy
+		alive bool
+	}{
+		{"auth ok", &model.Proxy{IP: "127.0.0.1", Port: addr.Port, Protocol: "socks5", Username: "user", Password: "pw"}, true},
+		{"wrong user", &model.Proxy{IP: "127.0.0.1", Port: addr.Port, Protocol: "socks5", Username: "other", Password: "pw"}, false},
+		{"no creds", &model.Proxy{IP: "127.0.0.1", Port: addr.Port, Protocol: "socks5"}, false},
+	}
+	for _, tt := range tests {
+		result, err := c.Check(context.Background(), tt.proxy)
+		if err != nil {
+			t.Fatalf("%s: Check returned error: %v", tt.name, err)
+		}
+		if result.Alive != tt.alive {
+			t.Errorf("%s: Alive = %v, want %v", tt.name, result.Alive, tt.alive)
+		}
+	}
+
+	// An HTTP server listed as SOCKS5 is a mismatch.
+	p := testProxy(t, "ok")
+	p.Protocol = "socks5"
+	probe, _ := c.newProbe(p)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := (&HandshakeValidator{TargetURL: "http://target.test/"}).Validate(ctx, probe); !errors.Is(err, ErrProtocolMismatch) {
+		t.Errorf("Expected ErrProtocolMismatch, got %v", err)
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	cfg := PipelineConfig{TargetURL: "http://t/", HTTPSURL: "https://t/", JudgeURL: "http://j/"}
+
+	pipeline, err := NewPipeline("tcp,handshake,http,https?,anonymity?", cfg)
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+	var names []string
+	for _, v := range pipeline {
+		names = append(names, v.Name())
+	}
+	if fmt.Sprint(names) != "[tcp handshake http https anonymity]" {
+		t.Errorf("Unexpected pipeline: %v", names)
+	}
+	if _, ok := pipeline[3].(optional); !ok {
+		t.Errorf("Expected https to be optional")
+	}
+
+	for _, spec := range []string{"", "tcp,bogus", "content"} {
+		if _, err := NewPipeline(spec, cfg); err == nil {
+			t.Errorf("NewPipeline(%q) expected error", spec)
+		}
+	}
+}
+
+func TestClassifyAnonymity(t *testing.T) {
+	const real = "198.51.100.1"
+	tests := []struct {
+		exitIP  string
+		headers map[string]string
+		want    string
+	}{
+		{"203.0.113.7", map[string]string{"User-Agent": "x"}, model.AnonymityElite},
+		{"203.0.113.7", map[string]string{"Via": "1.1 squid"}, model.AnonymityAnonymous},
+		{"203.0.113.7", map[string]string{"X-Forwarded-For": real}, model.AnonymityTransparent},
+		{real, nil, model.AnonymityTransparent},
+		{"203.0.113.7", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := classifyAnonymity(tt.exitIP, tt.headers, real); got != tt.want {
+			t.Errorf("classifyAnonymity(%q, %v) = %q, want %q", tt.exitIP, tt.headers, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"proxypool/internal/ipfilter"
+	"proxypool/internal/model"
+)
+
+// Prechecker does a bare TCP connect with a short timeout. It is cheap enough
+// to run with far higher concurrency than the full check, and weeds out the
+// closed ports that make up most scraped lists.
+type Prechecker struct {
+	Timeout time.Duration
+
+	// Guard, if set, refuses to dial private/reserved addresses.
+	Guard *ipfilter.Filter
+}
+
+func NewPrechecker(timeout time.Duration) *Prechecker {
+	return &Prechecker{Timeout: timeout}
+}
+
+// Check returns nil if the proxy accepts TCP connections.
+func (pc *Prechecker) Check(ctx context.Context, p *model.Proxy) error {
+	dialer := &net.Dialer{
+		Timeout: pc.Timeout,
+		Control: pc.Guard.Control,
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Address())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
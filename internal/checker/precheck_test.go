@@ -0,0 +1,44 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"proxypool/internal/ipfilter"
+	"proxypool/internal/model"
+)
+
+func TestPrechecker_Check(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// Grab a port and release it so nothing listens there.
+	closedLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := closedLn.Addr().(*net.TCPAddr).Port
+	closedLn.Close()
+	defer ln.Close()
+
+	pc := NewPrechecker(500 * time.Millisecond)
+	if err := pc.Check(context.Background(), &model.Proxy{IP: "127.0.0.1", Port: open}); err != nil {
+		t.Errorf("Open port failed pre-check: %v", err)
+	}
+
+	err = pc.Check(context.Background(), &model.Proxy{IP: "127.0.0.1", Port: closed})
+	if got := DialFailure(err); got != "refused" {
+		t.Errorf("DialFailure(%v) = %q, want refused", err, got)
+	}
+
+	pc.Guard = ipfilter.New(nil)
+	err = pc.Check(context.Background(), &model.Proxy{IP: "127.0.0.1", Port: open})
+	if got := DialFailure(err); got != "denied" {
+		t.Errorf("DialFailure(%v) = %q, want denied", err, got)
+	}
+}
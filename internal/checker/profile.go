@@ -0,0 +1,124 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// Profile is a named set of targets a proxy has to pass, typically to know
+// whether it works against a particular site.
+type Profile struct {
+	Name       string          `json:"name"`
+	Targets    []ProfileTarget `json:"targets"`
+	RateLimits RateLimits      `json:"rate_limits"`
+}
+
+// RateLimits caps how many checks per second may hit one target host, come
+// from one proxy subnet (/24 for IPv4, /48 for IPv6) or from one ASN, so
+// that targets don't ban the checker and providers don't block it. Zero
+// means unlimited.
+type RateLimits struct {
+	Target float64 `json:"target"`
+	Subnet float64 `json:"subnet"`
+	ASN    float64 `json:"asn"`
+}
+
+// Hosts returns the hosts the profile's targets are on.
+func (p Profile) Hosts() []string {
+	var hosts []string
+	for _, t := range p.Targets {
+		hosts = appendHost(hosts, t.URL)
+	}
+	return hosts
+}
+
+// ProfileTarget is one URL of a profile and what its response must look like.
+type ProfileTarget struct {
+	URL      string `json:"url"`
+	Status   []int  `json:"status,omitempty"`   // accepted status codes; default 2xx and 3xx
+	Contains string `json:"contains,omitempty"` // substring the body must contain
+}
+
+// LoadProfiles reads profiles from a JSON file holding an array of profiles:
+//
+//	[{"name": "google", "targets": [{"url": "https://www.google.com/", "status": [200], "contains": "<title>Google"}],
+//	  "rate_limits": {"target": 20, "subnet": 1}}]
+func LoadProfiles(path string) ([]Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open profiles: %w", err)
+	}
+	defer f.Close()
+	return ParseProfiles(f)
+}
+
+// ParseProfiles decodes and validates a JSON array of profiles.
+func ParseProfiles(r io.Reader) ([]Profile, error) {
+	var profiles []Profile
+	if err := json.NewDecoder(r).Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("decode profiles: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("profile without a name")
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate profile %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		if len(p.Targets) == 0 {
+			return nil, fmt.Errorf("profile %q has no targets", p.Name)
+		}
+		if l := p.RateLimits; l.Target < 0 || l.Subnet < 0 || l.ASN < 0 {
+			return nil, fmt.Errorf("profile %q: negative rate limit", p.Name)
+		}
+		for _, t := range p.Targets {
+			u, err := url.Parse(t.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("profile %q: invalid target url %q", p.Name, t.URL)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// CheckProfile runs every target of the profile through the proxy, stopping
+// at the first failure. Like Check, a failure is reported in the result, not
+// as an error.
+func (c *Checker) CheckProfile(ctx context.Context, p *model.Proxy, profile Profile) (*model.ProfileResult, error) {
+	probe, err := c.newProbe(p)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.ProfileResult{ProxyID: p.ID, Profile: profile.Name}
+	start := time.Now()
+	for _, t := range profile.Targets {
+		v := &HTTPValidator{URL: t.URL, Status: t.Status, Contains: t.Contains}
+		if err := c.runTarget(ctx, v, probe); err != nil {
+			res.Error = fmt.Sprintf("%s: %v", t.URL, err)
+			break
+		}
+	}
+	res.Passed = res.Error == ""
+	res.LatencyMS = int(time.Since(start).Milliseconds())
+	res.CheckedAt = time.Now()
+	return res, nil
+}
+
+// runTarget gives each target the full check timeout.
+func (c *Checker) runTarget(ctx context.Context, v Validator, probe *Probe) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	return v.Validate(ctx, probe)
+}
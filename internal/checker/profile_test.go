@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := ParseProfiles(strings.NewReader(`[
+		{"name": "google", "targets": [{"url": "https://www.google.com/", "status": [200], "contains": "Google"}]},
+		{"name": "two", "targets": [{"url": "http://a.test/"}, {"url": "http://b.test:8080/"}, {"url": "http://a.test/x"}],
+		 "rate_limits": {"target": 20, "subnet": 0.5}}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseProfiles failed: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].Targets[0].Status[0] != 200 || len(profiles[1].Targets) != 3 {
+		t.Errorf("Unexpected profiles: %+v", profiles)
+	}
+	if got := profiles[1].RateLimits; got != (RateLimits{Target: 20, Subnet: 0.5}) {
+		t.Errorf("RateLimits = %+v", got)
+	}
+	if got := profiles[1].Hosts(); !slices.Equal(got, []string{"a.test", "b.test"}) {
+		t.Errorf("Hosts() = %v", got)
+	}
+
+	for _, bad := range []string{
+		`{"name": "x"}`,
+		`[{"targets": [{"url": "http://a.test/"}]}]`,
+		`[{"name": "x", "targets": []}]`,
+		`[{"name": "x", "targets": [{"url": "ftp://a.test/"}]}]`,
+		`[{"name": "x", "targets": [{"url": "http://a.test/"}], "rate_limits": {"asn": -1}}]`,
+		`[{"name": "x", "targets": [{"url": "http://a.test/"}]}, {"name": "x", "targets": [{"url": "http://a.test/"}]}]`,
+	} {
+		if _, err := ParseProfiles(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseProfiles(%s) expected error", bad)
+		}
+	}
+}
+
+func TestChecker_CheckProfile(t *testing.T) {
+	p := testProxy(t, "<title>Example</title>")
+	p.ID = 42
+	c := NewChecker("http://target.test/", 2*time.Second)
+
+	tests := []struct {
+		name    string
+		profile Profile
+		passed  bool
+	}{
+		{"pass", Profile{Name: "ok", Targets: []ProfileTarget{{URL: "http://a.test/", Status: []int{200}, Contains: "Example"}, {URL: "http://b.test/"}}}, true},
+		{"wrong status", Profile{Name: "status", Targets: []ProfileTarget{{URL: "http://a.test/", Status: []int{204}}}}, false},
+		{"second target", Profile{Name: "body", Targets: []ProfileTarget{{URL: "http://a.test/"}, {URL: "http://b.test/", Contains: "captcha"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.CheckProfile(context.Background(), p, tt.profile)
+			if err != nil {
+				t.Fatalf("CheckProfile returned error: %v", err)
+			}
+			if res.Passed != tt.passed || res.ProxyID != 42 || res.Profile != tt.profile.Name || res.CheckedAt.IsZero() {
+				t.Errorf("Unexpected result: %+v", res)
+			}
+			if !tt.passed && res.Error == "" {
+				t.Errorf("Expected a failure reason")
+			}
+		})
+	}
+}
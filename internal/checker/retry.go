@@ -0,0 +1,92 @@
+package checker
+
+import (
+	"context"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// Retry re-runs failed checks before they count, so a single timeout
+// doesn't flip a healthy proxy to dead.
+type Retry struct {
+	// Attempts is how many times a failed check is retried.
+	Attempts int
+
+	// Delay is the pause before each retry.
+	Delay time.Duration
+
+	// Targets, if set, replace TargetURL on retries, in turn, so a flaky
+	// target doesn't fail the proxy twice.
+	Targets []string
+
+	// Admit, if set, is asked before each retry with the hosts it will send
+	// requests to, e.g. to apply rate limits. A refusal ends the check with
+	// the last result. Admitting the first attempt is up to the caller.
+	Admit func(ctx context.Context, p *model.Proxy, hosts []string) bool
+}
+
+// Retryable reports whether a check that failed with this class might pass
+// on a second try. Failures that say something about the proxy itself
+// (closed port, wrong protocol, credentials) are final.
+func Retryable(failure string) bool {
+	switch failure {
+	case FailureRefused, FailureUnreachable, FailureDenied, FailureAuthRequired, FailureProtocolMismatch:
+		return false
+	}
+	return true
+}
+
+// target returns the target URL for the given attempt (0 is the first).
+func (r Retry) target(attempt int, def string) string {
+	if attempt == 0 || len(r.Targets) == 0 {
+		return def
+	}
+	return r.Targets[(attempt-1)%len(r.Targets)]
+}
+
+// retarget returns a copy of pipeline with validators aimed at from aimed at
+// to instead.
+func retarget(pipeline []Validator, from, to string) []Validator {
+	if from == to {
+		return pipeline
+	}
+	out := make([]Validator, len(pipeline))
+	for i, v := range pipeline {
+		out[i] = retargetOne(v, from, to)
+	}
+	return out
+}
+
+func retargetOne(v Validator, from, to string) Validator {
+	switch v := v.(type) {
+	case optional:
+		return optional{retargetOne(v.Validator, from, to)}
+	case *HTTPValidator:
+		if v.URL == from {
+			c := *v
+			c.URL = to
+			return &c
+		}
+	case *HandshakeValidator:
+		if v.TargetURL == from {
+			return &HandshakeValidator{TargetURL: to}
+		}
+	}
+	return v
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
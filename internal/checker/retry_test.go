@@ -0,0 +1,123 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+func TestChecker_Retry(t *testing.T) {
+	// The proxy can't reach bad.test, and fails the first request it gets.
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 || r.URL.Host == "bad.test" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	proxy := &model.Proxy{IP: u.Hostname(), Port: port, Protocol: "http"}
+
+	tests := []struct {
+		name         string
+		target       string
+		retry        Retry
+		wantAlive    bool
+		wantAttempts int
+	}{
+		{"no retry", "http://good.test/", Retry{}, false, 1},
+		{"transient", "http://good.test/", Retry{Attempts: 2}, true, 2},
+		{"bad target", "http://bad.test/", Retry{Attempts: 2}, false, 3},
+		{"alternate target", "http://bad.test/", Retry{Attempts: 1, Targets: []string{"http://good.test/"}}, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			c := NewChecker(tt.target, 2*time.Second)
+			c.Retry = tt.retry
+			c.Retry.Delay = time.Millisecond
+
+			result, err := c.Check(context.Background(), proxy)
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if result.Alive != tt.wantAlive || result.Attempts != tt.wantAttempts {
+				t.Errorf("Alive = %v after %d attempts, want %v after %d", result.Alive, result.Attempts, tt.wantAlive, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestChecker_RetryFinalFailure(t *testing.T) {
+	c := NewChecker("http://target.test/", 2*time.Second)
+	c.Retry = Retry{Attempts: 3, Delay: time.Millisecond}
+
+	result, err := c.Check(context.Background(), &model.Proxy{IP: "127.0.0.1", Port: 1, Protocol: "http"})
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if result.Failure != FailureRefused || result.Attempts != 1 {
+		t.Errorf("Refused connections shouldn't be retried: %+v", result)
+	}
+}
+
+func TestChecker_RetryAdmit(t *testing.T) {
+	// The proxy can't reach bad.test.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "bad.test" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	proxy := &model.Proxy{IP: u.Hostname(), Port: port, Protocol: "http"}
+
+	tests := []struct {
+		name         string
+		allow        int // retries admitted
+		wantAlive    bool
+		wantAttempts int
+		wantAsked    [][]string
+	}{
+		{"admitted", 2, true, 3, [][]string{{"bad.test"}, {"good.test"}}},
+		{"refused", 0, false, 1, [][]string{{"bad.test"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked [][]string
+			c := NewChecker("http://bad.test/", 2*time.Second)
+			c.Retry = Retry{
+				Attempts: 2,
+				Delay:    time.Millisecond,
+				Targets:  []string{"http://bad.test/x", "http://good.test/"},
+				Admit: func(ctx context.Context, p *model.Proxy, hosts []string) bool {
+					asked = append(asked, hosts)
+					return len(asked) <= tt.allow
+				},
+			}
+
+			result, err := c.Check(context.Background(), proxy)
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if result.Alive != tt.wantAlive || result.Attempts != tt.wantAttempts {
+				t.Errorf("Alive = %v after %d attempts, want %v after %d", result.Alive, result.Attempts, tt.wantAlive, tt.wantAttempts)
+			}
+			if !slices.EqualFunc(asked, tt.wantAsked, slices.Equal) {
+				t.Errorf("Admit asked for %v, want %v", asked, tt.wantAsked)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// minThroughputSample is the least we need to have downloaded for a
+	// measurement to mean anything.
+	minThroughputSample = 64 << 10
+
+	// defaultThroughputTimeout bounds the download when Timeout is unset.
+	defaultThroughputTimeout = 10 * time.Second
+)
+
+// ThroughputValidator downloads a sized payload (e.g. the judge's
+// /bytes?n=5242880) through the proxy and records the rate in
+// CheckResult.ThroughputKBps. The download has its own deadline instead of
+// a share of the check timeout, so it neither gets cut short by the steps
+// before it nor leaves the steps after it without time. If the deadline
+// hits mid-download, the rate is computed from what arrived, so slow
+// proxies still get a number.
+type ThroughputValidator struct {
+	URL string
+
+	// MinKBps, if set, fails proxies slower than this.
+	MinKBps int
+
+	// Timeout bounds the download. Defaults to 10s.
+	Timeout time.Duration
+}
+
+func (v *ThroughputValidator) Name() string {
+	return "throughput"
+}
+
+func (v *ThroughputValidator) ownTimeout() time.Duration {
+	if v.Timeout > 0 {
+		return v.Timeout
+	}
+	return defaultThroughputTimeout
+}
+
+func (v *ThroughputValidator) Validate(ctx context.Context, probe *Probe) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", v.URL, nil)
+	if err != nil {
+		return fmt.Errorf("bad throughput request: %w", err)
+	}
+	// Compressed transfers would overstate the rate.
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := probe.UntimedClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("throughput request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("throughput returned status %d", resp.StatusCode)
+	}
+
+	// Time the body only: connection setup is what latency measures.
+	start := time.Now()
+	n, err := io.Copy(io.Discard, resp.Body)
+	elapsed := time.Since(start)
+	if err != nil && !isDeadline(err) {
+		return fmt.Errorf("throughput download: %w", err)
+	}
+	if n < minThroughputSample {
+		return fmt.Errorf("throughput download: only %d bytes", n)
+	}
+
+	kbps := int(float64(n) / 1024 / max(elapsed.Seconds(), 0.001))
+	probe.Result.ThroughputKBps = max(kbps, 1)
+
+	if v.MinKBps > 0 && kbps < v.MinKBps {
+		return fmt.Errorf("throughput %d KB/s below minimum %d KB/s", kbps, v.MinKBps)
+	}
+	return nil
+}
+
+func isDeadline(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,81 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"proxypool/internal/judge"
+)
+
+func TestThroughputValidator(t *testing.T) {
+	judgeServer := httptest.NewServer(judge.Handler())
+	defer judgeServer.Close()
+	p := forwardProxy(t, "")
+
+	tests := []struct {
+		name      string
+		validator ThroughputValidator
+		alive     bool
+	}{
+		{"measured", ThroughputValidator{URL: judgeServer.URL + "/bytes?n=1048576"}, true},
+		{"too small", ThroughputValidator{URL: judgeServer.URL + "/bytes?n=1024"}, false},
+		{"below minimum", ThroughputValidator{URL: judgeServer.URL + "/bytes?n=1048576", MinKBps: 1 << 30}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(judgeServer.URL, 5*time.Second)
+			c.Pipeline = []Validator{&tt.validator}
+
+			result, err := c.Check(context.Background(), p)
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if result.Alive != tt.alive {
+				t.Fatalf("Alive = %v, want %v (%+v)", result.Alive, tt.alive, result)
+			}
+			if tt.alive && result.ThroughputKBps <= 0 {
+				t.Errorf("Expected a throughput measurement, got %+v", result)
+			}
+		})
+	}
+}
+
+// A download slower than the check timeout runs under its own deadline and
+// leaves the validators after it their share of the check timeout.
+func TestThroughputValidator_OwnTimeout(t *testing.T) {
+	chunk := strings.Repeat("x", 32<<10)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bytes" {
+			w.Write([]byte("ok"))
+			return
+		}
+		for range 8 {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer slow.Close()
+
+	c := NewChecker(slow.URL, 500*time.Millisecond)
+	c.Pipeline = []Validator{
+		&ThroughputValidator{URL: slow.URL + "/bytes", Timeout: 5 * time.Second},
+		&HTTPValidator{URL: slow.URL + "/"},
+	}
+
+	result, err := c.Check(context.Background(), forwardProxy(t, ""))
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if !result.Alive {
+		t.Fatalf("Expected alive, got %+v", result)
+	}
+	if result.ThroughputKBps <= 0 || result.ThroughputKBps > 320 {
+		t.Errorf("Expected the whole download measured, got %d KB/s", result.ThroughputKBps)
+	}
+}
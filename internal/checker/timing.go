@@ -0,0 +1,81 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// phaseTimer collects httptrace events for one request.
+type phaseTimer struct {
+	mu sync.Mutex
+	start, connectStart, connectDone, tlsStart, tlsDone,
+	gotConn, wroteRequest, firstByte time.Time
+}
+
+// traceTimings returns a context that records the phases of the request it
+// is used for.
+func traceTimings(ctx context.Context) (context.Context, *phaseTimer) {
+	t := &phaseTimer{start: time.Now()}
+	set := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	trace := &httptrace.ClientTrace{
+		ConnectStart:         func(string, string) { set(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart:    func() { set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { set(&t.gotConn) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// timings converts the recorded events into phases, with the request ending
+// now. The proxy handshake is whatever happened between the TCP connect and
+// the connection being handed over, minus TLS.
+func (t *phaseTimer) timings() model.Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := model.Timings{
+		ConnectMS: ms(t.connectStart, t.connectDone),
+		TLSMS:     ms(t.tlsStart, t.tlsDone),
+		TTFBMS:    ms(t.wroteRequest, t.firstByte),
+		TotalMS:   ms(t.start, time.Now()),
+	}
+	if !t.gotConn.IsZero() {
+		res.HandshakeMS = max(0, ms(t.connectDone, t.gotConn)-res.TLSMS)
+	}
+	return res
+}
+
+// ms returns the milliseconds between two events, 0 if either is missing.
+func ms(from, to time.Time) int {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return int(to.Sub(from).Milliseconds())
+}
+
+// recordTimings stores the phases of the first request through the proxy.
+// Later requests only fill in TLS, so a plain HTTP check followed by an
+// HTTPS one yields a complete breakdown.
+func (p *Probe) recordTimings(t *phaseTimer) {
+	got := t.timings()
+	switch {
+	case p.Result.Timings == (model.Timings{}):
+		p.Result.Timings = got
+	case p.Result.Timings.TLSMS == 0:
+		p.Result.Timings.TLSMS = got.TLSMS
+	}
+}
@@ -0,0 +1,60 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+func TestPhaseTimer_Timings(t *testing.T) {
+	base := time.Now().Add(-time.Second)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+
+	timer := &phaseTimer{
+		start:        at(0),
+		connectStart: at(0),
+		connectDone:  at(30),
+		tlsStart:     at(80),
+		tlsDone:      at(120),
+		gotConn:      at(120),
+		wroteRequest: at(121),
+		firstByte:    at(321),
+	}
+	got := timer.timings()
+	want := model.Timings{ConnectMS: 30, HandshakeMS: 50, TLSMS: 40, TTFBMS: 200}
+	got.TotalMS = 0 // depends on the clock
+	if got != want {
+		t.Errorf("timings() = %+v, want %+v", got, want)
+	}
+
+	// Missing phases are 0, not negative.
+	if got := (&phaseTimer{start: at(0), connectStart: at(0)}).timings(); got.ConnectMS != 0 || got.HandshakeMS != 0 {
+		t.Errorf("Expected empty phases, got %+v", got)
+	}
+}
+
+func TestChecker_Check_Timings(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	c := NewChecker(slow.URL, 2*time.Second)
+	c.Pipeline = []Validator{&HTTPValidator{URL: slow.URL}, &HTTPValidator{URL: slow.URL, Contains: "never"}}
+
+	result, err := c.Check(context.Background(), forwardProxy(t, ""))
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	if result.Alive || result.FailedValidator != "http" {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	// The target is slow, not the proxy: it shows up as time to first byte.
+	if result.Timings.TTFBMS < 50 || result.Timings.TotalMS < result.Timings.TTFBMS {
+		t.Errorf("Unexpected timings on a failed check: %+v", result.Timings)
+	}
+}
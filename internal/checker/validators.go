@@ -0,0 +1,308 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// ErrProtocolMismatch means the proxy answered, but not in the protocol it
+// is listed as.
+var ErrProtocolMismatch = errors.New("protocol mismatch")
+
+// maxContentBody limits how much of a page the content validator reads.
+const maxContentBody = 1 << 20
+
+// TCPValidator checks that the proxy accepts TCP connections.
+type TCPValidator struct{}
+
+func (v *TCPValidator) Name() string {
+	return "tcp"
+}
+
+func (v *TCPValidator) Validate(ctx context.Context, probe *Probe) error {
+	conn, err := probe.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HandshakeValidator speaks the first step of the proxy's protocol to make
+// sure it really is what it is listed as: the SOCKS5 greeting (and
+// username/password authentication), a SOCKS4a CONNECT, or a plain HTTP
+// proxy request.
+type HandshakeValidator struct {
+	// TargetURL is the destination used by SOCKS4 CONNECT and HTTP requests.
+	TargetURL string
+}
+
+func (v *HandshakeValidator) Name() string {
+	return "handshake"
+}
+
+func (v *HandshakeValidator) Validate(ctx context.Context, probe *Probe) error {
+	target, err := url.Parse(v.TargetURL)
+	if err != nil {
+		return fmt.Errorf("invalid target url: %w", err)
+	}
+
+	conn, err := probe.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	p := probe.Proxy
+	switch p.Protocol {
+	case model.ProtocolSOCKS5:
+		return socks5Handshake(conn, p.Username, p.Password)
+	case model.ProtocolSOCKS4:
+		return socks4Connect(conn, target, p.Username)
+	case model.ProtocolHTTPS:
+		tlsConn := tls.Client(conn, &tls.Config{
+			// Proxies rarely have certificates for their IP; we only care that it speaks TLS.
+			InsecureSkipVerify: true,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("%w: tls: %v", ErrProtocolMismatch, err)
+		}
+		return httpProxyRequest(tlsConn, target, p)
+	default:
+		return httpProxyRequest(conn, target, p)
+	}
+}
+
+func socks5Handshake(conn net.Conn, username, password string) error {
+	greeting := []byte{0x05, 0x01, 0x00} // no authentication
+	if username != "" {
+		greeting = []byte{0x05, 0x01, 0x02} // username/password
+	}
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return fmt.Errorf("%w: socks5 greeting: %v", ErrProtocolMismatch, err)
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("%w: socks5 version %d", ErrProtocolMismatch, reply[0])
+	}
+
+	switch reply[1] {
+	case 0x00:
+		return nil
+	case 0x02:
+		if username == "" {
+			return fmt.Errorf("%w: socks5", ErrProxyAuth)
+		}
+		// RFC 1929
+		req := []byte{0x01, byte(len(username))}
+		req = append(req, username...)
+		req = append(req, byte(len(password)))
+		req = append(req, password...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return fmt.Errorf("socks5 auth: %w", err)
+		}
+		if reply[1] != 0x00 {
+			return fmt.Errorf("%w: socks5 credentials rejected", ErrProxyAuth)
+		}
+		return nil
+	default:
+		return fmt.Errorf("socks5 proxy offered no acceptable method (%#x)", reply[1])
+	}
+}
+
+func socks4Connect(conn net.Conn, target *url.URL, userID string) error {
+	port, err := strconv.Atoi(target.Port())
+	if err != nil {
+		port = 80
+		if target.Scheme == "https" {
+			port = 443
+		}
+	}
+
+	// SOCKS4a: IP 0.0.0.1 tells the proxy to resolve the hostname that follows.
+	req := []byte{0x04, 0x01, 0, 0, 0, 0, 0, 1}
+	binary.BigEndian.PutUint16(req[2:4], uint16(port))
+	req = append(req, userID...)
+	req = append(req, 0)
+	req = append(req, target.Hostname()...)
+	req = append(req, 0)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	var reply [8]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return fmt.Errorf("%w: socks4 reply: %v", ErrProtocolMismatch, err)
+	}
+	if reply[0] != 0x00 {
+		return fmt.Errorf("%w: socks4 reply version %d", ErrProtocolMismatch, reply[0])
+	}
+	if reply[1] != 0x5a {
+		return fmt.Errorf("socks4 request rejected (%#x)", reply[1])
+	}
+	return nil
+}
+
+// httpProxyRequest sends an absolute-form request and expects any HTTP
+// response other than 407.
+func httpProxyRequest(conn net.Conn, target *url.URL, p *model.Proxy) error {
+	req, err := http.NewRequest("HEAD", target.String(), nil)
+	if err != nil {
+		return fmt.Errorf("bad request: %w", err)
+	}
+	if p.HasAuth() {
+		req.SetBasicAuth(p.Username, p.Password)
+		req.Header.Set("Proxy-Authorization", req.Header.Get("Authorization"))
+		req.Header.Del("Authorization")
+	}
+	if err := req.WriteProxy(conn); err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProtocolMismatch, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		return ErrProxyAuth
+	}
+	return nil
+}
+
+// HTTPSValidator checks that the proxy can tunnel TLS: it CONNECTs to an
+// HTTPS URL and completes a verified TLS handshake with the target through
+// the tunnel. The outcome is recorded in CheckResult.HTTPS even when the
+// validator is optional.
+type HTTPSValidator struct {
+	URL string
+}
+
+func (v *HTTPSValidator) Name() string {
+	return "https"
+}
+
+func (v *HTTPSValidator) Validate(ctx context.Context, probe *Probe) error {
+	ok := false
+	defer func() { probe.Result.HTTPS = &ok }()
+
+	ctx, timer := traceTimings(ctx)
+	defer probe.recordTimings(timer)
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", v.URL, nil)
+	if err != nil {
+		return fmt.Errorf("bad request: %w", err)
+	}
+	if req.URL.Scheme != "https" {
+		return fmt.Errorf("https validator needs an https URL, got %q", v.URL)
+	}
+
+	resp, err := probe.Client().Do(req)
+	if err != nil {
+		return fmt.Errorf("https tunnel: %w", err)
+	}
+	resp.Body.Close()
+
+	// Any response over a verified session proves the tunnel works; the
+	// status is the target's business.
+	if resp.TLS == nil || !resp.TLS.HandshakeComplete {
+		return errors.New("https tunnel: no TLS session")
+	}
+	ok = true
+	return nil
+}
+
+// HTTPValidator requests a URL through the proxy and expects a 2xx or 3xx
+// response (or one of Status). For https URLs this exercises CONNECT
+// tunnelling.
+type HTTPValidator struct {
+	URL    string
+	Method string // defaults to GET
+
+	// Status, if set, lists the accepted status codes.
+	Status []int
+
+	// Contains, if set, must appear in the response body.
+	Contains string
+
+	name string
+}
+
+func (v *HTTPValidator) Name() string {
+	if v.name != "" {
+		return v.name
+	}
+	return "http"
+}
+
+func (v *HTTPValidator) Validate(ctx context.Context, probe *Probe) error {
+	method := v.Method
+	if method == "" {
+		method = "GET"
+	}
+	ctx, timer := traceTimings(ctx)
+	defer probe.recordTimings(timer)
+
+	req, err := http.NewRequestWithContext(ctx, method, v.URL, nil)
+	if err != nil {
+		return fmt.Errorf("bad request: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := probe.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !v.acceptStatus(resp.StatusCode) {
+		return &StatusError{Code: resp.StatusCode}
+	}
+
+	// The first request through the proxy sets its latency.
+	if probe.Result.LatencyMS == 0 {
+		probe.Result.LatencyMS = max(1, int(time.Since(start).Milliseconds()))
+	}
+
+	if v.Contains == "" {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxContentBody))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if !strings.Contains(string(body), v.Contains) {
+		return fmt.Errorf("%w: response does not contain %q", ErrContentMismatch, v.Contains)
+	}
+	return nil
+}
+
+func (v *HTTPValidator) acceptStatus(code int) bool {
+	if len(v.Status) == 0 {
+		return code >= 200 && code < 400
+	}
+	return slices.Contains(v.Status, code)
+}
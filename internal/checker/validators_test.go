@@ -0,0 +1,89 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"proxypool/internal/model"
+)
+
+// connectProxy starts an HTTP proxy that only supports CONNECT, optionally
+// refusing to tunnel.
+func connectProxy(t *testing.T, allow bool) *model.Proxy {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || !allow {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(ts.Close)
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &model.Proxy{IP: u.Hostname(), Port: port, Protocol: "http"}
+}
+
+func TestHTTPSValidator(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	tlsConfig := &tls.Config{RootCAs: target.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+
+	tests := []struct {
+		name   string
+		allow  bool
+		config *tls.Config
+		want   bool
+	}{
+		{"tunnel", true, tlsConfig, true},
+		{"refused", false, tlsConfig, false},
+		{"untrusted certificate", true, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(target.URL, 2*time.Second)
+			c.TLSConfig = tt.config
+			c.Pipeline = []Validator{Optional(&HTTPSValidator{URL: target.URL})}
+
+			result, err := c.Check(context.Background(), connectProxy(t, tt.allow))
+			if err != nil {
+				t.Fatalf("Check returned error: %v", err)
+			}
+			if !result.Alive {
+				t.Fatalf("Optional https failure should not fail the check")
+			}
+			if result.HTTPS == nil || *result.HTTPS != tt.want {
+				t.Errorf("HTTPS = %v, want %v", result.HTTPS, tt.want)
+			}
+		})
+	}
+}
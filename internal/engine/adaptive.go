@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"proxypool/internal/checker"
+	"proxypool/internal/metrics"
+)
+
+const (
+	// adaptInterval is how often the number of active check workers is
+	// re-evaluated.
+	adaptInterval = 10 * time.Second
+
+	// timeoutJump is the rise in the timeout rate between two intervals that
+	// is read as saturation rather than bad proxies.
+	timeoutJump = 0.1
+
+	// fdHigh and fdGrow are the shares of the descriptor limit above which
+	// workers are shed, and below which they may be added.
+	fdHigh = 0.85
+	fdGrow = 0.7
+)
+
+// workerGate lets workers with an ID below the limit run and parks the rest.
+type workerGate struct {
+	mu      sync.Mutex
+	limit   int
+	changed chan struct{} // closed when limit changes
+}
+
+func newWorkerGate(limit int) *workerGate {
+	return &workerGate{limit: limit, changed: make(chan struct{})}
+}
+
+// wait blocks until worker id may run. It returns false if ctx is done first.
+func (g *workerGate) wait(ctx context.Context, id int) bool {
+	for {
+		g.mu.Lock()
+		limit, changed := g.limit, g.changed
+		g.mu.Unlock()
+		if id < limit {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (g *workerGate) current() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.limit
+}
+
+func (g *workerGate) setLimit(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n != g.limit {
+		g.limit = n
+		close(g.changed)
+		g.changed = make(chan struct{})
+	}
+}
+
+// precheckWorkers caps want so that the pre-check sockets and max check
+// workers together stay below the share of the open file limit at which
+// check workers may still be added. Without a known limit want stands.
+func precheckWorkers(want, maxWorkers int, openFiles func() (int, int, bool)) int {
+	open, limit, ok := openFiles()
+	if !ok || limit <= 0 {
+		return want
+	}
+	room := int(float64(limit)*fdGrow) - open - maxWorkers
+	if room >= want {
+		return want
+	}
+	n := max(room, 1)
+	slog.Warn("Open file limit too low for the pre-check workers, starting fewer",
+		"precheck_workers", want, "started", n, "max_workers", maxWorkers, "open_fds", open, "fd_limit", limit)
+	return n
+}
+
+// concurrency adapts the number of active check workers between min and max.
+// It grows while every worker is busy and doing more of them raises the
+// check rate, and shrinks when timeouts jump, when growing stopped paying
+// off, or when file descriptors run short.
+type concurrency struct {
+	gate *workerGate
+
+	mu       sync.Mutex
+	min, max int
+
+	checks   atomic.Int64 // checks finished this interval
+	timeouts atomic.Int64 // of which timed out
+	busy     atomic.Int64 // workers inside a check right now
+
+	lastRate        float64 // checks per second in the previous interval
+	lastTimeoutRate float64
+	grew            bool // the previous adjustment added workers
+
+	openFiles func() (open, limit int, ok bool)
+}
+
+func newConcurrency(start, min, max int) *concurrency {
+	metrics.CheckWorkers.Set(int64(start))
+	return &concurrency{gate: newWorkerGate(start), min: min, max: max, openFiles: openFiles}
+}
+
+// bounds returns the current range.
+func (c *concurrency) bounds() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.min, c.max
+}
+
+// setBounds changes the range and moves the active workers into it at once.
+// Equal bounds pin the number.
+func (c *concurrency) setBounds(lo, hi int) {
+	c.mu.Lock()
+	c.min, c.max = lo, hi
+	c.mu.Unlock()
+
+	limit := c.gate.current()
+	if next := min(max(limit, lo), hi); next != limit {
+		c.gate.setLimit(next)
+		metrics.CheckWorkers.Set(int64(next))
+		slog.Info("Adjusted check workers", "from", limit, "to", next, "min", lo, "max", hi)
+	}
+}
+
+// observe records a finished check.
+func (c *concurrency) observe(failure string) {
+	c.checks.Add(1)
+	if failure == checker.FailureDialTimeout || failure == checker.FailureResponseTimeout {
+		c.timeouts.Add(1)
+	}
+}
+
+func (c *concurrency) run(ctx context.Context) {
+	ticker := time.NewTicker(adaptInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.adjust(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+func (c *concurrency) adjust(elapsed time.Duration) {
+	checks, timeouts := c.checks.Swap(0), c.timeouts.Swap(0)
+	rate := float64(checks) / elapsed.Seconds()
+	var timeoutRate float64
+	if checks > 0 {
+		timeoutRate = float64(timeouts) / float64(checks)
+	}
+	open, fdLimit, fdKnown := c.openFiles()
+	fdShare := 0.0
+	if fdKnown && fdLimit > 0 {
+		fdShare = float64(open) / float64(fdLimit)
+	}
+
+	limit := c.gate.current()
+	next := limit
+	switch {
+	case fdShare > fdHigh:
+		next = limit * 3 / 4
+	case checks > 0 && timeoutRate > c.lastTimeoutRate+timeoutJump:
+		next = limit * 9 / 10
+	case c.grew && rate < c.lastRate*0.95:
+		// More workers didn't mean more checks: the network is saturated.
+		next = limit * 9 / 10
+	case c.busy.Load() >= int64(limit)*9/10 && fdShare < fdGrow:
+		next = limit + max(limit/10, 1)
+	}
+	lo, hi := c.bounds()
+	next = min(max(next, lo), hi)
+
+	c.grew = next > limit
+	c.lastRate, c.lastTimeoutRate = rate, timeoutRate
+	if next != limit {
+		c.gate.setLimit(next)
+		metrics.CheckWorkers.Set(int64(next))
+		slog.Info("Adjusted check workers", "from", limit, "to", next,
+			"checks_per_sec", int(rate), "timeout_rate", timeoutRate, "open_fds", open, "fd_limit", fdLimit)
+	}
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// files returns an openFiles stub reporting open of limit descriptors.
+func files(open, limit int) func() (int, int, bool) {
+	return func() (int, int, bool) { return open, limit, true }
+}
+
+func TestConcurrency_Adjust(t *testing.T) {
+	tests := []struct {
+		name      string
+		min, max  int
+		openFiles func() (int, int, bool)
+
+		checks, timeouts, busy int64
+		lastRate, lastTimeouts float64
+		grew                   bool
+
+		want int
+	}{
+		{
+			name: "shed on fd", min: 10, max: 200, openFiles: files(900, 1000),
+			checks: 100, busy: 100,
+			want: 75,
+		},
+		{
+			name: "shrink on timeout jump", min: 10, max: 200, openFiles: files(100, 1000),
+			checks: 100, timeouts: 50, busy: 100, lastTimeouts: 0.2,
+			want: 90,
+		},
+		{
+			name: "steady timeouts", min: 10, max: 200, openFiles: files(100, 1000),
+			checks: 100, timeouts: 50, lastTimeouts: 0.45,
+			want: 100,
+		},
+		{
+			name: "shrink when growth didn't pay", min: 10, max: 200, openFiles: files(100, 1000),
+			checks: 100, busy: 100, lastRate: 20, grew: true,
+			want: 90,
+		},
+		{
+			name: "grow when busy", min: 10, max: 200, openFiles: files(100, 1000),
+			checks: 100, busy: 95,
+			want: 110,
+		},
+		{
+			name: "grow without fd info", min: 10, max: 200, openFiles: func() (int, int, bool) { return 0, 0, false },
+			checks: 100, busy: 100,
+			want: 110,
+		},
+		{
+			name: "no growth near the fd limit", min: 10, max: 200, openFiles: files(750, 1000),
+			checks: 100, busy: 100,
+			want: 100,
+		},
+		{
+			name: "idle", min: 10, max: 200, openFiles: files(100, 1000),
+			checks: 100, busy: 10,
+			want: 100,
+		},
+		{
+			name: "clamp to max", min: 10, max: 105, openFiles: files(100, 1000),
+			checks: 100, busy: 100,
+			want: 105,
+		},
+		{
+			name: "clamp to min", min: 95, max: 200, openFiles: files(900, 1000),
+			checks: 100, busy: 100,
+			want: 95,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConcurrency(100, tt.min, tt.max)
+			c.openFiles = tt.openFiles
+			c.checks.Store(tt.checks)
+			c.timeouts.Store(tt.timeouts)
+			c.busy.Store(tt.busy)
+			c.lastRate, c.lastTimeoutRate, c.grew = tt.lastRate, tt.lastTimeouts, tt.grew
+
+			c.adjust(10 * time.Second)
+
+			if got := c.gate.current(); got != tt.want {
+				t.Errorf("limit = %d, want %d", got, tt.want)
+			}
+			if c.grew != (tt.want > 100) {
+				t.Errorf("grew = %v after moving to %d", c.grew, tt.want)
+			}
+			if c.checks.Load() != 0 || c.timeouts.Load() != 0 {
+				t.Error("Counters should be reset for the next interval")
+			}
+			if c.lastRate != float64(tt.checks)/10 {
+				t.Errorf("lastRate = %v, want %v", c.lastRate, float64(tt.checks)/10)
+			}
+		})
+	}
+}
+
+func TestWorkerGate_SetLimit(t *testing.T) {
+	g := newWorkerGate(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !g.wait(ctx, 1) {
+		t.Fatal("Worker below the limit should run")
+	}
+
+	done := make(chan bool)
+	go func() { done <- g.wait(ctx, 5) }()
+
+	select {
+	case <-done:
+		t.Fatal("Worker above the limit should be parked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.setLimit(4) // Not enough for worker 5
+	select {
+	case <-done:
+		t.Fatal("Worker 5 should stay parked at limit 4")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.setLimit(6)
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatal("Woken worker should run")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Raising the limit should wake the parked worker")
+	}
+}
+
+func TestWorkerGate_WaitCancelled(t *testing.T) {
+	g := newWorkerGate(1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan bool)
+	go func() { done <- g.wait(ctx, 3) }()
+	cancel()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Wait should report the cancelled context")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Cancelling should release the parked worker")
+	}
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"proxypool/internal/model"
+)
+
+// Pipeline stages that can be paused while the engine runs. A paused stage
+// finishes what it holds and takes nothing new, so pausing StageProduce
+// drains the queues behind it.
+const (
+	StageScrape   = "scrape"   // periodic scraping of the sources
+	StageProduce  = "produce"  // fetching due proxies into the check queue
+	StagePrecheck = "precheck" // TCP pre-check
+	StageCheck    = "check"    // full check
+	StageProfiles = "profiles" // check profiles
+	StageResolve  = "resolve"  // hostname resolution
+)
+
+// Stages lists the stages in pipeline order.
+var Stages = []string{StageScrape, StageProduce, StagePrecheck, StageCheck, StageProfiles, StageResolve}
+
+var (
+	ErrUnknownStage  = errors.New("unknown stage")
+	ErrUnknownSource = errors.New("unknown source")
+	ErrWorkerBounds  = errors.New("invalid worker bounds")
+)
+
+// pauseGate holds back a stage while it is paused.
+type pauseGate struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{} // closed on resume
+}
+
+func newStages() map[string]*pauseGate {
+	stages := make(map[string]*pauseGate, len(Stages))
+	for _, name := range Stages {
+		stages[name] = &pauseGate{}
+	}
+	return stages
+}
+
+// wait blocks while the stage is paused. It returns false if ctx is done.
+func (g *pauseGate) wait(ctx context.Context) bool {
+	for ctx.Err() == nil {
+		g.mu.Lock()
+		paused, resumed := g.paused, g.resumed
+		g.mu.Unlock()
+		if !paused {
+			return true
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+		}
+	}
+	return false
+}
+
+func (g *pauseGate) set(paused bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case paused && !g.paused:
+		g.resumed = make(chan struct{})
+	case !paused && g.paused:
+		close(g.resumed)
+	}
+	g.paused = paused
+}
+
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// queues are the channels between the stages of a running engine.
+type queues struct {
+	jobs    chan *model.Proxy
+	live    chan *model.Proxy // nil without a pre-check
+	results chan result
+}
+
+// Pause stops stage from taking new work until Resume.
+func (e *Engine) Pause(stage string) error {
+	return e.setPaused(stage, true)
+}
+
+// Resume lets a paused stage continue.
+func (e *Engine) Resume(stage string) error {
+	return e.setPaused(stage, false)
+}
+
+func (e *Engine) setPaused(stage string, paused bool) error {
+	g, ok := e.stages[stage]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownStage, stage)
+	}
+	g.set(paused)
+	slog.Info("Stage control", "stage", stage, "paused", paused)
+	return nil
+}
+
+// Status is a snapshot of the engine for the admin API.
+type Status struct {
+	Paused  map[string]bool `json:"paused"` // By stage
+	Queues  map[string]int  `json:"queues"` // Proxies waiting in each queue
+	Workers WorkerStatus    `json:"workers"`
+	Leader  bool            `json:"leader"` // Runs the singleton tasks
+}
+
+// WorkerStatus describes the check workers.
+type WorkerStatus struct {
+	Active  int `json:"active"`  // Allowed to run by the adaptive gate
+	Busy    int `json:"busy"`    // Inside a check right now
+	Min     int `json:"min"`     // Lower bound of Active
+	Max     int `json:"max"`     // Upper bound of Active
+	Started int `json:"started"` // Goroutines started; the ceiling for Max
+}
+
+func (e *Engine) Status() Status {
+	s := Status{
+		Paused: make(map[string]bool, len(e.stages)),
+		Queues: make(map[string]int),
+		Leader: e.leading.Load(),
+	}
+	for name, g := range e.stages {
+		s.Paused[name] = g.isPaused()
+	}
+	if q := e.queues.Load(); q != nil {
+		s.Queues["jobs"] = len(q.jobs)
+		s.Queues["results"] = len(q.results)
+		if q.live != nil {
+			s.Queues["live"] = len(q.live)
+		}
+	}
+	lo, hi := e.workers.bounds()
+	s.Workers = WorkerStatus{
+		Active:  e.workers.gate.current(),
+		Busy:    int(e.workers.busy.Load()),
+		Min:     lo,
+		Max:     hi,
+		Started: e.cfg.MaxWorkers,
+	}
+	return s
+}
+
+// SetWorkers changes the bounds the number of active check workers adapts
+// within. Equal bounds pin it. Max can't exceed the configured MaxWorkers.
+func (e *Engine) SetWorkers(lo, hi int) error {
+	if lo < 1 || lo > hi || hi > e.cfg.MaxWorkers {
+		return fmt.Errorf("%w: need 1 <= min <= max <= %d", ErrWorkerBounds, e.cfg.MaxWorkers)
+	}
+	e.workers.setBounds(lo, hi)
+	return nil
+}
+
+// Scrape scrapes the named source now, or every source if name is empty,
+// whether or not scraping is paused or this instance leads. It returns how
+// many proxies each source saved; a source that failed is missing from the
+// counts and its error is joined into the returned one.
+func (e *Engine) Scrape(ctx context.Context, name string) (map[string]int, error) {
+	saved := make(map[string]int)
+	var errs []error
+	found := false
+	for _, src := range e.sources {
+		if name != "" && src.Name() != name {
+			continue
+		}
+		found = true
+		n, err := e.scrape(ctx, src)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.Name(), err))
+			continue
+		}
+		saved[src.Name()] = n
+	}
+	if !found {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSource, name)
+	}
+	return saved, errors.Join(errs...)
+}
+
+// CheckReport is the outcome of an on-demand check.
+type CheckReport struct {
+	Proxy *model.Proxy       `json:"proxy"`
+	Check *model.CheckRecord `json:"check"`
+}
+
+// CheckProxy checks the proxy with the given ID now, outside the queues and
+// rate limits, and saves the result like any other check.
+func (e *Engine) CheckProxy(ctx context.Context, id int64) (*CheckReport, error) {
+	p, err := e.repo.GetProxy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.IP == "" {
+		return nil, fmt.Errorf("proxy %d has not been resolved yet", id)
+	}
+
+	check := e.check(ctx, p)
+	if err := e.repo.Update(ctx, p); err != nil {
+		return nil, fmt.Errorf("save result: %w", err)
+	}
+	if err := e.repo.SaveChecks(ctx, []*model.CheckRecord{check}); err != nil {
+		return nil, fmt.Errorf("save check: %w", err)
+	}
+	return &CheckReport{Proxy: p, Check: check}, nil
+}
@@ -2,20 +2,81 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"proxypool/internal/checker"
 	"proxypool/internal/geoip"
+	"proxypool/internal/ipfilter"
+	"proxypool/internal/metrics"
 	"proxypool/internal/model"
 	"proxypool/internal/scraper"
 	"proxypool/internal/storage"
 )
 
 type Config struct {
+	// NumWorkers is the number of check workers to start with. Between
+	// MinWorkers and MaxWorkers, the engine adapts it to the timeout rate,
+	// check throughput and file descriptor headroom. Unset bounds pin it.
 	NumWorkers int
-	BatchSize  int
+	MinWorkers int
+	MaxWorkers int
+
+	BatchSize int
+
+	// Lanes split each batch of checks between serving, new, recently
+	// alive and long dead proxies. Defaults to storage.DefaultLanes.
+	Lanes []storage.Lane
+
+	// RateLimits throttle the checks per target host, proxy subnet and
+	// ASN. Profiles carry their own.
+	RateLimits checker.RateLimits
+
+	// Filter drops private, reserved and denied addresses before they are saved.
+	Filter *ipfilter.Filter
+
+	// Precheck, if set, runs a TCP connect on PrecheckWorkers workers before
+	// the full check so closed ports never reach it. Their sockets count
+	// against the same open file limit as the check workers', so Run starts
+	// fewer of them if the limit can't hold PrecheckWorkers plus MaxWorkers
+	// below the point where check workers are added.
+	Precheck        *checker.Prechecker
+	PrecheckWorkers int
+
+	// Profiles are re-run against alive proxies once their result is older
+	// than ProfileInterval, on ProfileWorkers workers.
+	Profiles        []checker.Profile
+	ProfileInterval time.Duration
+	ProfileWorkers  int
+
+	// CheckHistoryRetention is how long check history (with timings) is kept.
+	CheckHistoryRetention time.Duration
+
+	// PromoteAfter is how many consecutive passed checks a new proxy needs
+	// before it is served. Defaults to 1.
+	PromoteAfter int
+
+	// ResolveInterval is how often hostname proxies are looked up again,
+	// using Resolver (nil means the system resolver).
+	ResolveInterval time.Duration
+	Resolver        Resolver
+
+	// Leader, if set, decides whether this instance runs the singleton
+	// tasks (scraping, profiles, resolving, pruning) when several share the
+	// database. Checking is shared between all of them regardless. Nil
+	// means the instance is alone.
+	Leader Leader
+}
+
+// result is a checked proxy on its way to the writer, with the check to add
+// to its history.
+type result struct {
+	proxy *model.Proxy
+	check *model.CheckRecord
 }
 
 type Engine struct {
@@ -24,39 +85,103 @@ type Engine struct {
 	chk     *checker.Checker
 	geo     *geoip.Service
 	cfg     Config
+	workers *concurrency
+
+	limits        *rateLimiter
+	profileLimits map[string]*rateLimiter
+
+	// stages can be paused from the admin API; queues are set once Run
+	// has made them.
+	stages map[string]*pauseGate
+	queues atomic.Pointer[queues]
+
+	// leading is set while the singleton tasks run here.
+	leading atomic.Bool
 }
 
 func New(repo storage.ProxyRepository, srcList []scraper.Source, chk *checker.Checker, geo *geoip.Service, cfg Config) *Engine {
 	if cfg.NumWorkers <= 0 {
 		cfg.NumWorkers = 50
 	}
+	if cfg.MinWorkers <= 0 || cfg.MinWorkers > cfg.NumWorkers {
+		cfg.MinWorkers = cfg.NumWorkers
+	}
+	if cfg.MaxWorkers < cfg.NumWorkers {
+		cfg.MaxWorkers = cfg.NumWorkers
+	}
 	if cfg.BatchSize <= 0 {
 		cfg.BatchSize = 100
 	}
+	if len(cfg.Lanes) == 0 {
+		cfg.Lanes, _ = storage.ParseLanes(storage.DefaultLanes)
+	}
+	if cfg.PrecheckWorkers <= 0 {
+		cfg.PrecheckWorkers = cfg.NumWorkers * 4
+	}
+	if cfg.ProfileInterval <= 0 {
+		cfg.ProfileInterval = 30 * time.Minute
+	}
+	if cfg.ProfileWorkers <= 0 {
+		cfg.ProfileWorkers = 50
+	}
+	if cfg.CheckHistoryRetention <= 0 {
+		cfg.CheckHistoryRetention = 7 * 24 * time.Hour
+	}
+	if cfg.PromoteAfter <= 0 {
+		cfg.PromoteAfter = 1
+	}
+	if cfg.ResolveInterval <= 0 {
+		cfg.ResolveInterval = 5 * time.Minute
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	profileLimits := make(map[string]*rateLimiter, len(cfg.Profiles))
+	for _, profile := range cfg.Profiles {
+		profileLimits[profile.Name] = newRateLimiter(profile.RateLimits, profile.Hosts())
+	}
+	// Workers admit the first attempt of a check; retries, which may go to
+	// other targets, are admitted as they come.
+	limits := newRateLimiter(cfg.RateLimits, chk.AttemptHosts(0))
+	chk.Retry.Admit = func(ctx context.Context, p *model.Proxy, hosts []string) bool {
+		return limits.admitHosts(ctx, p, hosts, maxRateWait)
+	}
 	return &Engine{
 		repo:    repo,
 		sources: srcList,
 		chk:     chk,
 		geo:     geo,
 		cfg:     cfg,
+		workers: newConcurrency(cfg.NumWorkers, cfg.MinWorkers, cfg.MaxWorkers),
+
+		limits:        limits,
+		profileLimits: profileLimits,
+
+		stages: newStages(),
 	}
 }
 
 func (e *Engine) Run(ctx context.Context) {
 	var wg sync.WaitGroup
 
-	// 1. Scrape Scheduler (Runs periodically)
+	// 1. Singleton tasks (scraping, profiles, resolving, pruning), on the leader only
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		e.runScrapingLoop(ctx)
+		if e.cfg.Leader == nil {
+			e.runSingletons(ctx)
+			return
+		}
+		e.cfg.Leader.Lead(ctx, e.runSingletons)
 	}()
 
-	// Pipeline: DB -> (jobs) -> Workers -> (results) -> DB Writer
+	// Pipeline: DB -> (jobs) -> [Pre-check Workers -> (checks)] -> Workers -> (results) -> DB Writer
 
 	// Channels
 	jobChan := make(chan *model.Proxy, e.cfg.BatchSize*2)
-	resultChan := make(chan *model.Proxy, e.cfg.BatchSize*2)
+	resultChan := make(chan result, e.cfg.BatchSize*2)
+	checkChan := (<-chan *model.Proxy)(jobChan)
+	q := &queues{jobs: jobChan, results: resultChan}
 
 	// 2. DB Producer (Fetches unchecked proxies)
 	wg.Add(1)
@@ -66,16 +191,47 @@ func (e *Engine) Run(ctx context.Context) {
 		e.runProducer(ctx, jobChan)
 	}()
 
-	// 3. Check Workers
-	// Start N workers
+	// 3. Pre-check Workers
+	// Dead endpoints go straight to the writer; live ones on to the checkers.
+	if e.cfg.Precheck != nil {
+		liveChan := make(chan *model.Proxy, e.cfg.BatchSize*2)
+		checkChan = liveChan
+		q.live = liveChan
+
+		n := precheckWorkers(e.cfg.PrecheckWorkers, e.cfg.MaxWorkers, e.workers.openFiles)
+		precheckWg := &sync.WaitGroup{}
+		precheckWg.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer precheckWg.Done()
+				e.runPrecheckWorker(ctx, jobChan, liveChan, resultChan)
+			}()
+		}
+		// Checkers finish after liveChan closes, so resultChan stays open for us.
+		go func() {
+			precheckWg.Wait()
+			close(liveChan)
+		}()
+	}
+
+	e.queues.Store(q)
+
+	// 4. Check Workers
+	// Start MaxWorkers; the gate decides how many of them run.
 	workerWg := &sync.WaitGroup{}
-	workerWg.Add(e.cfg.NumWorkers)
-	for i := 0; i < e.cfg.NumWorkers; i++ {
+	workerWg.Add(e.cfg.MaxWorkers)
+	for i := 0; i < e.cfg.MaxWorkers; i++ {
 		go func() {
 			defer workerWg.Done()
-			e.runWorker(ctx, jobChan, resultChan)
+			e.runWorker(ctx, i, checkChan, resultChan)
 		}()
 	}
+	// Even with fixed bounds, which the admin API may loosen
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		e.workers.run(ctx)
+	}()
 
 	// Wait for workers to finish (when jobChan closes), then close resultChan
 	go func() {
@@ -83,7 +239,7 @@ func (e *Engine) Run(ctx context.Context) {
 		close(resultChan)
 	}()
 
-	// 4. DB Writer (Batch Updates)
+	// 5. DB Writer (Batch Updates)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -114,26 +270,42 @@ func (e *Engine) runScrapingLoop(ctx context.Context) {
 
 func (e *Engine) scrapeAll(ctx context.Context) {
 	for _, src := range e.sources {
-		if ctx.Err() != nil {
+		if !e.stages[StageScrape].wait(ctx) {
 			return
 		}
-		slog.Info("Scraping", "source", src.Name())
-		proxies, err := src.Fetch(ctx)
-		if err != nil {
+		if _, err := e.scrape(ctx, src); err != nil {
 			slog.Error("Scrape failed", "source", src.Name(), "error", err)
-			continue
-		}
-		if len(proxies) > 0 {
-			if err := e.repo.SaveBatch(ctx, proxies); err != nil {
-				slog.Error("SaveBatch failed", "error", err)
-			} else {
-				slog.Info("Saved proxies", "count", len(proxies), "source", src.Name())
-			}
 		}
 	}
 }
 
-// runProducer fetches proxies from DB and sends to jobChan
+// scrape fetches src and saves what passes the filter, returning how many
+// proxies were saved.
+func (e *Engine) scrape(ctx context.Context, src scraper.Source) (int, error) {
+	slog.Info("Scraping", "source", src.Name())
+	proxies, err := src.Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	proxies, dropped := e.cfg.Filter.Apply(proxies)
+	if dropped > 0 {
+		metrics.IngestDropped.Add(src.Name(), int64(dropped))
+		slog.Info("Filtered proxies", "count", dropped, "source", src.Name())
+	}
+
+	if len(proxies) == 0 {
+		return 0, nil
+	}
+	if err := e.repo.SaveBatch(ctx, proxies); err != nil {
+		return 0, fmt.Errorf("save: %w", err)
+	}
+	metrics.IngestSaved.Add(src.Name(), int64(len(proxies)))
+	slog.Info("Saved proxies", "count", len(proxies), "source", src.Name())
+	return len(proxies), nil
+}
+
+// runProducer fetches proxies from DB, lane by lane, and sends to jobChan
 func (e *Engine) runProducer(ctx context.Context, jobChan chan<- *model.Proxy) {
 	ticker := time.NewTicker(1 * time.Second) // Poll DB frequently
 	defer ticker.Stop()
@@ -143,16 +315,15 @@ func (e *Engine) runProducer(ctx context.Context, jobChan chan<- *model.Proxy) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			// Fetch batch
-			proxies, err := e.repo.GetProxiesToCheck(ctx, e.cfg.BatchSize)
-			if err != nil {
-				slog.Error("Producer fetch failed", "error", err)
-				continue
+			if !e.stages[StageProduce].wait(ctx) {
+				return
 			}
+			// Fetch batch, split between the check lanes
+			proxies := e.nextBatch(ctx)
 			if len(proxies) == 0 {
 				continue // Nothing to do, wait for next tick
 			}
-			
+
 			// Push to queue. Blocking if queue full (which provides backpressure to DB fetching)
 			for _, p := range proxies {
 				select {
@@ -165,40 +336,167 @@ func (e *Engine) runProducer(ctx context.Context, jobChan chan<- *model.Proxy) {
 	}
 }
 
-// runWorker reads jobs, checks proxy, sends to resultChan
-func (e *Engine) runWorker(ctx context.Context, jobChan <-chan *model.Proxy, resultChan chan<- *model.Proxy) {
-	for p := range jobChan {
-		if ctx.Err() != nil {
+// runPrecheckWorker reads jobs and TCP-connects to each proxy. Reachable
+// proxies go to liveChan, dead ones are marked and sent to resultChan with a
+// check failed by the "precheck" validator.
+// Proxies that were alive get the benefit of the doubt on a timeout and go
+// on to the full check, which retries.
+func (e *Engine) runPrecheckWorker(ctx context.Context, jobChan <-chan *model.Proxy, liveChan chan<- *model.Proxy, resultChan chan<- result) {
+	for e.stages[StagePrecheck].wait(ctx) {
+		p, ok := <-jobChan
+		if !ok || ctx.Err() != nil {
 			return
 		}
 
-		res, err := e.chk.Check(ctx, p)
-		now := time.Now()
-		p.LastCheckedAt = &now
-
-		if err != nil || !res.Alive {
-			p.LatencyMS = 0 // Dead
-		} else {
-			p.LatencyMS = res.LatencyMS
-			if e.geo != nil {
-				iso, _, err := e.geo.Lookup(p.IP)
-				if err == nil && iso != "" {
-					p.Country = iso
+		start := time.Now()
+		if err := e.cfg.Precheck.Check(ctx, p); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failure := checker.DialFailure(err)
+			metrics.PrecheckFailed.Add(failure, 1)
+			if p.LatencyMS > 0 && failure == checker.FailureDialTimeout {
+				select {
+				case liveChan <- p:
+					continue
+				case <-ctx.Done():
+					return
 				}
 			}
+			markDead(p)
+			p.LastFailure = failure
+			connectMS := int(time.Since(start).Milliseconds())
+			check := &model.CheckRecord{
+				ProxyID:         p.ID,
+				CheckedAt:       *p.LastCheckedAt,
+				FailedValidator: "precheck",
+				Failure:         failure,
+				Attempts:        1,
+				Timings:         model.Timings{ConnectMS: connectMS, TotalMS: connectMS},
+			}
+			select {
+			case resultChan <- result{proxy: p, check: check}:
+			case <-ctx.Done():
+				return
+			}
+			continue
 		}
+		metrics.PrecheckPassed.Add(1)
 
 		select {
-		case resultChan <- p:
+		case liveChan <- p:
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// markDead records a failed check.
+func markDead(p *model.Proxy) {
+	now := time.Now()
+	p.LastCheckedAt = &now
+	p.LatencyMS = 0
+	p.SuccessStreak = 0
+}
+
+// markAlive records a passed check, promoting the proxy to serving once it
+// has passed PromoteAfter checks in a row.
+func (e *Engine) markAlive(p *model.Proxy, now time.Time) {
+	p.LastAliveAt = &now
+	p.SuccessStreak++
+	if p.PromotedAt == nil && p.SuccessStreak >= e.cfg.PromoteAfter {
+		p.PromotedAt = &now
+		metrics.ProxiesPromoted.Add(1)
+	}
+}
+
+// runWorker reads jobs, checks proxy, sends to resultChan. Worker id only
+// takes jobs while the adaptive gate lets it and the check stage isn't
+// paused. Proxies the rate limits hold back for too long are dropped
+// without a result; they are still due and come round again once their
+// claim expires.
+func (e *Engine) runWorker(ctx context.Context, id int, jobChan <-chan *model.Proxy, resultChan chan<- result) {
+	for e.workers.gate.wait(ctx, id) && e.stages[StageCheck].wait(ctx) {
+		p, ok := <-jobChan
+		if !ok || ctx.Err() != nil {
+			return
+		}
+		if !e.limits.admit(ctx, p, maxRateWait) {
+			continue
+		}
+
+		e.workers.busy.Add(1)
+		check := e.check(ctx, p)
+		e.workers.busy.Add(-1)
+
+		select {
+		case resultChan <- result{proxy: p, check: check}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check runs the full check on p and records the outcome on it.
+func (e *Engine) check(ctx context.Context, p *model.Proxy) *model.CheckRecord {
+	res, err := e.chk.Check(ctx, p)
+	now := time.Now()
+	p.LastCheckedAt = &now
+
+	check := &model.CheckRecord{ProxyID: p.ID, CheckedAt: now, Failure: checker.FailureOther, Attempts: 1}
+	if err == nil {
+		check.Alive = res.Alive
+		check.FailedValidator = res.FailedValidator
+		check.Failure = res.Failure
+		check.Timings = res.Timings
+		check.Attempts = res.Attempts
+	}
+	e.workers.observe(check.Failure)
+	if check.Attempts > 1 {
+		metrics.CheckRetries.Add(int64(check.Attempts - 1))
+		if check.Alive {
+			metrics.ChecksRecovered.Add(1)
+		}
+	}
+
+	if !check.Alive {
+		p.LatencyMS = 0 // Dead
+		p.SuccessStreak = 0
+		p.LastFailure = check.Failure
+		metrics.CheckFailures.Add(check.Failure, 1)
+		return check
+	}
+
+	metrics.ChecksAlive.Add(1)
+	e.markAlive(p, now)
+	p.LastFailure = ""
+	p.LatencyMS = res.LatencyMS
+	if res.ExitIP != "" {
+		p.ExitIP = res.ExitIP
+	}
+	if res.Anonymity != "" {
+		p.Anonymity = res.Anonymity
+	}
+	if res.HTTPS != nil {
+		p.SupportsHTTPS = res.HTTPS
+	}
+	if res.IPv6 != nil {
+		p.SupportsIPv6 = res.IPv6
+	}
+	if res.ThroughputKBps > 0 {
+		p.ThroughputKBps = res.ThroughputKBps
+	}
+	if res.Tampered != nil {
+		p.Tampered, p.TamperReason = *res.Tampered, res.TamperReason
+	}
+	enrich(e.geo, p)
+	return check
+}
+
 // runWriter collects results and periodically batch updates DB
-func (e *Engine) runWriter(ctx context.Context, resultChan <-chan *model.Proxy) {
+func (e *Engine) runWriter(ctx context.Context, resultChan <-chan result) {
 	batch := make([]*model.Proxy, 0, e.cfg.BatchSize)
+	checks := make([]*model.CheckRecord, 0, e.cfg.BatchSize)
 	ticker := time.NewTicker(5 * time.Second) // Force flush interval
 	defer ticker.Stop()
 
@@ -211,6 +509,12 @@ func (e *Engine) runWriter(ctx context.Context, resultChan <-chan *model.Proxy)
 			}
 			batch = batch[:0] // clear
 		}
+		if len(checks) > 0 {
+			if err := e.repo.SaveChecks(ctx, checks); err != nil {
+				slog.Error("Writer check history failed", "count", len(checks), "error", err)
+			}
+			checks = checks[:0]
+		}
 	}
 
 	for {
@@ -220,15 +524,39 @@ func (e *Engine) runWriter(ctx context.Context, resultChan <-chan *model.Proxy)
 			return
 		case <-ticker.C:
 			flush()
-		case p, ok := <-resultChan:
+		case r, ok := <-resultChan:
 			if !ok {
 				flush()
 				return
 			}
-			batch = append(batch, p)
+			batch = append(batch, r.proxy)
+			if r.check != nil {
+				checks = append(checks, r.check)
+			}
 			if len(batch) >= e.cfg.BatchSize {
 				flush()
 			}
 		}
 	}
 }
+
+// runHistoryPruner deletes check history older than CheckHistoryRetention, hourly.
+func (e *Engine) runHistoryPruner(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := e.repo.PruneChecks(ctx, time.Now().Add(-e.cfg.CheckHistoryRetention))
+		if err != nil {
+			slog.Error("Pruning check history failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Pruned check history", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// openFiles returns the number of open file descriptors and the soft limit.
+func openFiles() (open, limit int, ok bool) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, 0, false
+	}
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, 0, false
+	}
+	return len(entries), int(rl.Cur), true
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package engine
+
+// openFiles is only implemented on Linux; elsewhere the worker count adapts
+// without file descriptor headroom.
+func openFiles() (open, limit int, ok bool) {
+	return 0, 0, false
+}
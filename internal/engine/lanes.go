@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"proxypool/internal/metrics"
+	"proxypool/internal/model"
+	"proxypool/internal/storage"
+)
+
+// nextBatch claims up to BatchSize proxies due for a check, split between
+// the lanes by weight. A lane with fewer due proxies than its share leaves
+// the rest to the other lanes, in priority order. The batch is ordered by
+// lane priority.
+func (e *Engine) nextBatch(ctx context.Context) []*model.Proxy {
+	lanes := e.cfg.Lanes
+	total := 0
+	for _, lane := range lanes {
+		total += lane.Weight
+	}
+
+	now := time.Now()
+	fetched := make([][]*model.Proxy, len(lanes))
+	shares := make([]int, len(lanes))
+	left := e.cfg.BatchSize
+	for i, lane := range lanes {
+		shares[i] = max(1, e.cfg.BatchSize*lane.Weight/total)
+		fetched[i] = e.fetchLane(ctx, lane, now, shares[i])
+		left -= len(fetched[i])
+	}
+
+	// Hand what the quiet lanes didn't use to the busy ones.
+	for i, lane := range lanes {
+		if left <= 0 {
+			break
+		}
+		if len(fetched[i]) < shares[i] {
+			continue // Lane drained
+		}
+		more := e.fetchLane(ctx, lane, now, left)
+		fetched[i] = append(fetched[i], more...)
+		left -= len(more)
+	}
+
+	var batch []*model.Proxy
+	seen := make(map[int64]bool)
+	for i, lane := range lanes {
+		n := 0
+		for _, p := range fetched[i] {
+			if !seen[p.ID] {
+				seen[p.ID] = true
+				batch = append(batch, p)
+				n++
+			}
+		}
+		if n > 0 {
+			metrics.LaneDispatched.Add(lane.Name, int64(n))
+		}
+	}
+	return batch
+}
+
+func (e *Engine) fetchLane(ctx context.Context, lane storage.Lane, now time.Time, limit int) []*model.Proxy {
+	proxies, err := e.repo.GetProxiesToCheck(ctx, lane.Name, now.Add(-lane.Interval), limit)
+	if err != nil {
+		slog.Error("Producer fetch failed", "lane", lane.Name, "error", err)
+		return nil
+	}
+	return proxies
+}
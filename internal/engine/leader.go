@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"proxypool/internal/metrics"
+)
+
+// Leader runs fn while this instance leads the ones sharing the database,
+// cancelling it when leadership is lost. See leader.Elector.
+type Leader interface {
+	Lead(ctx context.Context, fn func(ctx context.Context))
+}
+
+// runSingletons runs the tasks that only one instance may run until ctx is
+// cancelled: scraping, profile checks, hostname resolution and history
+// pruning.
+func (e *Engine) runSingletons(ctx context.Context) {
+	slog.Info("Starting singleton tasks")
+	e.leading.Store(true)
+	defer e.leading.Store(false)
+	metrics.Leader.Set(1)
+	defer metrics.Leader.Set(0)
+
+	var wg sync.WaitGroup
+
+	// Scrape Scheduler (Runs periodically)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		e.runScrapingLoop(ctx)
+	}()
+
+	// Check profiles (site-specific validation of alive proxies)
+	if len(e.cfg.Profiles) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.runProfileLoop(ctx)
+		}()
+	}
+
+	// Hostname resolution
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		e.runResolverLoop(ctx)
+	}()
+
+	// Check history pruning
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		e.runHistoryPruner(ctx)
+	}()
+
+	wg.Wait()
+	slog.Info("Stopped singleton tasks")
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"proxypool/internal/checker"
+	"proxypool/internal/model"
+)
+
+// runProfileLoop keeps check profile results fresh: every minute, proxies
+// whose result for a profile is older than ProfileInterval are re-run.
+func (e *Engine) runProfileLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		e.checkProfiles(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) checkProfiles(ctx context.Context) {
+	staleBefore := time.Now().Add(-e.cfg.ProfileInterval)
+	for _, profile := range e.cfg.Profiles {
+		for e.stages[StageProfiles].wait(ctx) {
+			proxies, err := e.repo.GetProxiesForProfile(ctx, profile.Name, staleBefore, e.cfg.BatchSize)
+			if err != nil {
+				slog.Error("Profile fetch failed", "profile", profile.Name, "error", err)
+				break
+			}
+			if len(proxies) == 0 {
+				break
+			}
+
+			results := e.runProfile(ctx, profile, proxies)
+			if ctx.Err() != nil {
+				return
+			}
+			if len(results) == 0 {
+				break // All held back by the rate limits; try again next round
+			}
+			if err := e.repo.SaveProfileResults(ctx, results); err != nil {
+				slog.Error("Saving profile results failed", "profile", profile.Name, "error", err)
+				break
+			}
+
+			passed := 0
+			for _, r := range results {
+				if r.Passed {
+					passed++
+				}
+			}
+			slog.Info("Checked profile", "profile", profile.Name, "count", len(results), "passed", passed)
+		}
+	}
+}
+
+// runProfile checks the proxies against profile on up to ProfileWorkers
+// goroutines, within the profile's rate limits. Every proxy checked gets a
+// result so it isn't fetched again straight away; those the limits held
+// back get none.
+func (e *Engine) runProfile(ctx context.Context, profile checker.Profile, proxies []*model.Proxy) []*model.ProfileResult {
+	limits := e.profileLimits[profile.Name]
+	results := make([]*model.ProfileResult, len(proxies))
+	sem := make(chan struct{}, e.cfg.ProfileWorkers)
+	var wg sync.WaitGroup
+
+	for i, p := range proxies {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+
+			if !limits.admit(ctx, p, maxRateWait) {
+				return
+			}
+			res, err := e.chk.CheckProfile(ctx, p, profile)
+			if err != nil {
+				res = &model.ProfileResult{ProxyID: p.ID, Profile: profile.Name, Error: err.Error(), CheckedAt: time.Now()}
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	return slices.DeleteFunc(results, func(r *model.ProfileResult) bool { return r == nil })
+}
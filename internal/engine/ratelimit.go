@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"proxypool/internal/checker"
+	"proxypool/internal/metrics"
+	"proxypool/internal/model"
+	"proxypool/internal/ratelimit"
+)
+
+// maxRateWait is how long a check waits for the rate limits before the
+// proxy sits this round out. It stays due and is fetched again.
+const maxRateWait = 5 * time.Second
+
+// Rate limit kinds, as reported in metrics.
+const (
+	limitTarget = "target"
+	limitSubnet = "subnet"
+	limitASN    = "asn"
+)
+
+// rateLimiter enforces one set of checker.RateLimits for checks that hit
+// hosts. Retries that hit other hosts are admitted with admitHosts.
+type rateLimiter struct {
+	hosts  []string
+	target *ratelimit.Limiter
+	subnet *ratelimit.Limiter
+	asn    *ratelimit.Limiter
+}
+
+func newRateLimiter(limits checker.RateLimits, hosts []string) *rateLimiter {
+	return &rateLimiter{
+		hosts:  hosts,
+		target: ratelimit.New(limits.Target, 0),
+		subnet: ratelimit.New(limits.Subnet, 0),
+		asn:    ratelimit.New(limits.ASN, 0),
+	}
+}
+
+type reservation struct {
+	limiter *ratelimit.Limiter
+	key     string
+}
+
+// admit waits until the limits allow checking p, for at most maxWait. If
+// they don't, or ctx is done first, it takes nothing and returns false.
+func (r *rateLimiter) admit(ctx context.Context, p *model.Proxy, maxWait time.Duration) bool {
+	return r.admitHosts(ctx, p, r.hosts, maxWait)
+}
+
+// admitHosts is admit for a check that hits hosts.
+func (r *rateLimiter) admitHosts(ctx context.Context, p *model.Proxy, hosts []string, maxWait time.Duration) bool {
+	var (
+		taken []reservation
+		wait  time.Duration
+	)
+	reserve := func(kind string, l *ratelimit.Limiter, key string) bool {
+		if l == nil || key == "" {
+			return true
+		}
+		d, ok := l.Reserve(key, maxWait)
+		if !ok {
+			release(taken)
+			metrics.RateLimited.Add(kind, 1)
+			return false
+		}
+		taken = append(taken, reservation{l, key})
+		wait = max(wait, d)
+		return true
+	}
+
+	if !reserve(limitSubnet, r.subnet, subnetKey(p.IP)) {
+		return false
+	}
+	if p.ASN != 0 && !reserve(limitASN, r.asn, strconv.Itoa(p.ASN)) {
+		return false
+	}
+	for _, host := range hosts {
+		if !reserve(limitTarget, r.target, host) {
+			return false
+		}
+	}
+
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			release(taken)
+			return false
+		}
+	}
+	return true
+}
+
+// release returns the tokens of a check that won't run.
+func release(taken []reservation) {
+	for _, t := range taken {
+		t.limiter.Cancel(t.key)
+	}
+}
+
+// subnetKey returns the /24 (IPv4) or /48 (IPv6) ip is in, or "" if ip
+// isn't an address.
+func subnetKey(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
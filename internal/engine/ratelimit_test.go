@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"proxypool/internal/checker"
+	"proxypool/internal/model"
+)
+
+func TestRateLimiter_AdmitCancelled(t *testing.T) {
+	r := newRateLimiter(checker.RateLimits{Target: 1}, []string{"target.test"})
+	p := &model.Proxy{}
+
+	if !r.admit(context.Background(), p, time.Second) {
+		t.Fatal("First check should be admitted at once")
+	}
+
+	// The second has to wait for the next token; cancel it meanwhile.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if r.admit(ctx, p, 2*time.Second) {
+		t.Fatal("Cancelled check should not be admitted")
+	}
+
+	// Its token is back: the next one is due in a second, not two.
+	if wait, ok := r.target.Reserve("target.test", 1500*time.Millisecond); !ok {
+		t.Errorf("Cancelled check kept its token (wait %v)", wait)
+	}
+}
+
+func TestRateLimiter_AdmitHosts(t *testing.T) {
+	r := newRateLimiter(checker.RateLimits{Target: 1}, []string{"first.test"})
+	p := &model.Proxy{}
+
+	if !r.admit(context.Background(), p, 0) {
+		t.Fatal("First attempt should be admitted")
+	}
+	// A retry to another target has its own budget; one to the same doesn't.
+	if !r.admitHosts(context.Background(), p, []string{"retry.test"}, 0) {
+		t.Error("Retry to another target should be admitted")
+	}
+	if r.admitHosts(context.Background(), p, []string{"first.test"}, 0) {
+		t.Error("Retry to the same target should wait for its token")
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"proxypool/internal/geoip"
+	"proxypool/internal/model"
+	"proxypool/internal/storage"
+)
+
+// enrich applies GeoIP data for the proxy's exit IP (or entry IP if the exit
+// is unknown). Lookup failures are ignored; the proxy keeps whatever it had.
+func enrich(geo *geoip.Service, p *model.Proxy) {
+	if geo == nil {
+		return
+	}
+	if info, err := geo.Enrich(p.GeoIP()); err == nil {
+		info.Apply(p)
+	}
+}
+
+// Reenrich walks every stored proxy and refreshes its GeoIP data, e.g. after
+// a database update. It returns the number of proxies written.
+func Reenrich(ctx context.Context, repo storage.ProxyRepository, geo *geoip.Service, batchSize int) (int, error) {
+	var (
+		afterID int64
+		total   int
+	)
+	for {
+		proxies, err := repo.ListPage(ctx, afterID, batchSize)
+		if err != nil {
+			return total, fmt.Errorf("list proxies: %w", err)
+		}
+		if len(proxies) == 0 {
+			return total, nil
+		}
+
+		for _, p := range proxies {
+			enrich(geo, p)
+		}
+		if err := repo.UpdateGeoBatch(ctx, proxies); err != nil {
+			return total, fmt.Errorf("update proxies: %w", err)
+		}
+
+		total += len(proxies)
+		afterID = proxies[len(proxies)-1].ID
+		slog.Info("Re-enriched batch", "count", len(proxies), "total", total)
+	}
+}
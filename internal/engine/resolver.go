@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"proxypool/internal/metrics"
+	"proxypool/internal/model"
+)
+
+// resolveWorkers bounds concurrent DNS lookups.
+const resolveWorkers = 50
+
+var errNoAllowedAddress = errors.New("no allowed address")
+
+// Resolver looks up the addresses of a hostname; *net.Resolver is one.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// runResolverLoop keeps hostname proxies resolved: every minute, proxies
+// resolved longer than ResolveInterval ago are looked up again.
+func (e *Engine) runResolverLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		e.resolveAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) resolveAll(ctx context.Context) {
+	staleBefore := time.Now().Add(-e.cfg.ResolveInterval)
+	for e.stages[StageResolve].wait(ctx) {
+		proxies, err := e.repo.GetProxiesToResolve(ctx, staleBefore, e.cfg.BatchSize)
+		if err != nil {
+			slog.Error("Resolver fetch failed", "error", err)
+			return
+		}
+		if len(proxies) == 0 {
+			return
+		}
+
+		resolutions := e.resolveBatch(ctx, proxies)
+		if ctx.Err() != nil {
+			return
+		}
+		if err := e.repo.SaveResolutions(ctx, resolutions); err != nil {
+			slog.Error("Saving resolutions failed", "error", err)
+			return
+		}
+	}
+}
+
+// resolveBatch looks up every proxy's hostname. Every proxy gets a
+// resolution, failed or not, so it isn't fetched again straight away.
+func (e *Engine) resolveBatch(ctx context.Context, proxies []*model.Proxy) []*model.Resolution {
+	resolutions := make([]*model.Resolution, len(proxies))
+	sem := make(chan struct{}, resolveWorkers)
+	var wg sync.WaitGroup
+
+	for i, p := range proxies {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+
+			res := &model.Resolution{ProxyID: p.ID, PreviousIP: p.IP}
+			ip, err := e.resolve(ctx, p.Host, p.IP)
+			res.ResolvedAt = time.Now()
+			if err != nil {
+				metrics.ResolutionFailures.Add(1)
+				slog.Debug("Resolving proxy failed", "host", p.Host, "error", err)
+			} else {
+				res.IP = ip
+			}
+			if res.Changed() {
+				metrics.ResolutionChanges.Add(1)
+				slog.Info("Proxy address changed", "host", p.Host, "from", p.IP, "to", ip)
+			}
+			resolutions[i] = res
+		}()
+	}
+	wg.Wait()
+	return resolutions
+}
+
+// resolve returns an address of host that passes the ingest filter. The
+// current address is kept while host still resolves to it, so round-robin
+// records don't count as changes.
+func (e *Engine) resolve(ctx context.Context, host, current string) (string, error) {
+	addrs, err := e.cfg.Resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return "", err
+	}
+
+	var allowed []string
+	for _, addr := range addrs {
+		if addr = addr.Unmap(); e.cfg.Filter.Allowed(addr) {
+			allowed = append(allowed, addr.String())
+		}
+	}
+	if len(allowed) == 0 {
+		return "", errNoAllowedAddress
+	}
+	if slices.Contains(allowed, current) {
+		return current, nil
+	}
+	// Prefer IPv4, which every checker host can reach.
+	for _, ip := range allowed {
+		if netip.MustParseAddr(ip).Is4() {
+			return ip, nil
+		}
+	}
+	return allowed[0], nil
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"proxypool/internal/ipfilter"
+)
+
+// stubResolver answers lookups from a map.
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	addrs := make([]netip.Addr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, netip.MustParseAddr(ip))
+	}
+	return addrs, nil
+}
+
+func TestEngine_Resolve(t *testing.T) {
+	e := &Engine{cfg: Config{
+		Filter: ipfilter.New(nil),
+		Resolver: stubResolver{
+			"rr.example":     {"1.1.1.1", "8.8.8.8"},
+			"dual.example":   {"2606:4700::1111", "1.1.1.1"},
+			"v6.example":     {"2606:4700::1111"},
+			"mapped.example": {"::ffff:9.9.9.9"},
+			"mixed.example":  {"10.0.0.1", "127.0.0.1", "9.9.9.9"},
+			"bogon.example":  {"10.0.0.1", "192.168.1.1", "::1"},
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		host    string
+		current string
+		want    string
+		wantErr error
+	}{
+		{"keeps current", "rr.example", "8.8.8.8", "8.8.8.8", nil},
+		{"takes first when current is gone", "rr.example", "4.4.4.4", "1.1.1.1", nil},
+		{"prefers IPv4", "dual.example", "", "1.1.1.1", nil},
+		{"keeps current IPv6", "dual.example", "2606:4700::1111", "2606:4700::1111", nil},
+		{"IPv6 only", "v6.example", "", "2606:4700::1111", nil},
+		{"unmaps IPv4", "mapped.example", "", "9.9.9.9", nil},
+		{"skips bogons", "mixed.example", "", "9.9.9.9", nil},
+		{"leaves current bogon", "mixed.example", "10.0.0.1", "9.9.9.9", nil},
+		{"only bogons", "bogon.example", "10.0.0.1", "", errNoAllowedAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.resolve(context.Background(), tt.host, tt.current)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := e.resolve(context.Background(), "missing.example", "1.1.1.1"); err == nil {
+		t.Error("Expected the lookup error")
+	}
+}
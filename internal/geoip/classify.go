@@ -0,0 +1,84 @@
+package geoip
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"proxypool/internal/model"
+)
+
+// DefaultHostingASNs are large cloud and hosting providers.
+var DefaultHostingASNs = []uint{
+	8075,   // Microsoft
+	9009,   // M247
+	12876,  // Scaleway
+	13335,  // Cloudflare
+	14061,  // DigitalOcean
+	14618,  // Amazon
+	15169,  // Google
+	16276,  // OVH
+	16509,  // Amazon
+	20473,  // Vultr (Choopa)
+	24940,  // Hetzner
+	31898,  // Oracle Cloud
+	37963,  // Alibaba
+	45102,  // Alibaba
+	51167,  // Contabo
+	60781,  // LeaseWeb
+	63949,  // Linode (Akamai)
+	132203, // Tencent Cloud
+	396982, // Google Cloud
+}
+
+// Classifier maps ASNs to a network type using configured lists.
+// ASNs on neither list are assumed residential.
+type Classifier struct {
+	hosting map[uint]bool
+	mobile  map[uint]bool
+}
+
+func NewClassifier(hosting, mobile []uint) *Classifier {
+	c := &Classifier{
+		hosting: make(map[uint]bool, len(hosting)),
+		mobile:  make(map[uint]bool, len(mobile)),
+	}
+	for _, asn := range hosting {
+		c.hosting[asn] = true
+	}
+	for _, asn := range mobile {
+		c.mobile[asn] = true
+	}
+	return c
+}
+
+// Classify returns one of the model.Network* types, or "" if asn is unknown
+// or the classifier is nil.
+func (c *Classifier) Classify(asn uint) string {
+	switch {
+	case c == nil || asn == 0:
+		return ""
+	case c.mobile[asn]:
+		return model.NetworkMobile
+	case c.hosting[asn]:
+		return model.NetworkHosting
+	}
+	return model.NetworkResidential
+}
+
+// ParseASNs parses ASNs such as "16509" or "AS16509".
+func ParseASNs(list []string) ([]uint, error) {
+	var out []uint
+	for _, s := range list {
+		s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "AS")
+		if s == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asn %q: %w", s, err)
+		}
+		out = append(out, uint(n))
+	}
+	return out, nil
+}
@@ -0,0 +1,40 @@
+package geoip
+
+import (
+	"testing"
+
+	"proxypool/internal/model"
+)
+
+func TestClassifier_Classify(t *testing.T) {
+	hosting, err := ParseASNs([]string{"AS16509", " 24940 "})
+	if err != nil {
+		t.Fatalf("ParseASNs failed: %v", err)
+	}
+	c := NewClassifier(hosting, []uint{21928})
+
+	tests := []struct {
+		asn  uint
+		want string
+	}{
+		{16509, model.NetworkHosting},
+		{24940, model.NetworkHosting},
+		{21928, model.NetworkMobile},
+		{7922, model.NetworkResidential},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.asn); got != tt.want {
+			t.Errorf("Classify(%d) = %q, want %q", tt.asn, got, tt.want)
+		}
+	}
+
+	var nilClassifier *Classifier
+	if got := nilClassifier.Classify(16509); got != "" {
+		t.Errorf("nil classifier returned %q", got)
+	}
+
+	if _, err := ParseASNs([]string{"ASX"}); err == nil {
+		t.Errorf("Expected error for invalid ASN")
+	}
+}
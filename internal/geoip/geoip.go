@@ -2,25 +2,47 @@ package geoip
 
 import (
 	"fmt"
+	"log/slog"
 	"net"
+	"sync"
 
 	"github.com/oschwald/geoip2-golang"
+
+	"proxypool/internal/model"
 )
 
 type Service struct {
-	db *geoip2.Reader
+	cityPath string
+	asnPath  string
+
+	// mu guards the readers. Lookups hold it for reading; Reload swaps the
+	// readers under the write lock, so a reader is never closed mid-lookup.
+	mu    sync.RWMutex
+	db    *geoip2.Reader // GeoLite2-City
+	asn   *geoip2.Reader // GeoLite2-ASN, optional
+	files map[string]fileState
+
+	// Classifier labels networks as hosting/residential/mobile by ASN.
+	// If nil, NetworkType is left empty.
+	Classifier *Classifier
 }
 
-func New(dbPath string) (*Service, error) {
-	db, err := geoip2.Open(dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open geoip db: %w", err)
+// New opens the City database and, if asnPath is not empty, the ASN database.
+func New(dbPath, asnPath string) (*Service, error) {
+	s := &Service{cityPath: dbPath, asnPath: asnPath}
+	if err := s.Reload(); err != nil {
+		return nil, err
 	}
-
-	return &Service{db: db}, nil
+	return s, nil
 }
 
 func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.asn != nil {
+		s.asn.Close()
+	}
 	return s.db.Close()
 }
 
@@ -30,6 +52,9 @@ func (s *Service) Lookup(ipStr string) (string, string, error) {
 		return "", "", fmt.Errorf("invalid IP address: %s", ipStr)
 	}
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	record, err := s.db.City(ip)
 	if err != nil {
 		return "", "", fmt.Errorf("geoip lookup failed: %w", err)
@@ -37,3 +62,81 @@ func (s *Service) Lookup(ipStr string) (string, string, error) {
 
 	return record.Country.IsoCode, record.Country.Names["en"], nil
 }
+
+// Info is the location and network of an IP address.
+type Info struct {
+	CountryISO  string
+	CountryName string
+	City        string
+	Region      string
+	Latitude    float64
+	Longitude   float64
+	ASN         uint
+	ASNOrg      string
+	NetworkType string // model.Network*
+}
+
+// Enrich looks the IP up in every loaded database. A failed ASN lookup is
+// logged and leaves the network fields empty; the location still counts.
+func (s *Service) Enrich(ipStr string) (*Info, error) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ipStr)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, err := s.db.City(ip)
+	if err != nil {
+		return nil, fmt.Errorf("geoip lookup failed: %w", err)
+	}
+
+	info := &Info{
+		CountryISO:  record.Country.IsoCode,
+		CountryName: record.Country.Names["en"],
+		City:        record.City.Names["en"],
+		Latitude:    record.Location.Latitude,
+		Longitude:   record.Location.Longitude,
+	}
+	if len(record.Subdivisions) > 0 {
+		info.Region = record.Subdivisions[0].Names["en"]
+	}
+
+	if s.asn != nil {
+		asn, err := s.asn.ASN(ip)
+		if err != nil {
+			slog.Debug("ASN lookup failed", "ip", ipStr, "error", err)
+			return info, nil
+		}
+		info.ASN = asn.AutonomousSystemNumber
+		info.ASNOrg = asn.AutonomousSystemOrganization
+		info.NetworkType = s.Classifier.Classify(info.ASN)
+	}
+
+	return info, nil
+}
+
+// Apply copies the lookup result onto the proxy. Empty fields don't
+// overwrite what the proxy already has.
+func (i *Info) Apply(p *model.Proxy) {
+	if i.CountryISO != "" {
+		p.Country = i.CountryISO
+	}
+	if i.City != "" {
+		p.City = i.City
+	}
+	if i.Region != "" {
+		p.Region = i.Region
+	}
+	if i.Latitude != 0 || i.Longitude != 0 {
+		p.Latitude, p.Longitude = i.Latitude, i.Longitude
+	}
+	if i.ASN != 0 {
+		p.ASN = int(i.ASN)
+		p.ASNOrg = i.ASNOrg
+	}
+	if i.NetworkType != "" {
+		p.NetworkType = i.NetworkType
+	}
+}
@@ -6,12 +6,12 @@ import (
 
 func TestService_Lookup(t *testing.T) {
 	// Assumes running from project root or having access to data dir relatively
-	// Adjust path as needed for test execution context. 
+	// Adjust path as needed for test execution context.
 	// For simplicity, we assume the test runs where data/ is available or we skip if not found.
-	
+
 	dbPath := "../../data/GeoLite2-City.mmdb"
-	
-	svc, err := New(dbPath)
+
+	svc, err := New(dbPath, "")
 	if err != nil {
 		t.Skipf("Skipping test: DB file not found at %s: %v", dbPath, err)
 	}
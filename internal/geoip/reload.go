@@ -0,0 +1,111 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// fileState identifies a version of a database file on disk.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileState, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// Reload opens fresh readers for the configured files and swaps them in.
+// In-flight lookups finish on the old readers, which are closed afterwards.
+// On error the current readers stay in place.
+func (s *Service) Reload() error {
+	files := make(map[string]fileState)
+
+	cityState, err := statFile(s.cityPath)
+	if err != nil {
+		return fmt.Errorf("failed to open geoip db: %w", err)
+	}
+	db, err := geoip2.Open(s.cityPath)
+	if err != nil {
+		return fmt.Errorf("failed to open geoip db: %w", err)
+	}
+	files[s.cityPath] = cityState
+
+	var asn *geoip2.Reader
+	if s.asnPath != "" {
+		asnState, err := statFile(s.asnPath)
+		if err == nil {
+			asn, err = geoip2.Open(s.asnPath)
+		}
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("failed to open asn db: %w", err)
+		}
+		files[s.asnPath] = asnState
+	}
+
+	s.mu.Lock()
+	oldDB, oldASN := s.db, s.asn
+	s.db, s.asn, s.files = db, asn, files
+	s.mu.Unlock()
+
+	// Nobody can hold the old readers any more: lookups take the read lock.
+	if oldDB != nil {
+		oldDB.Close()
+	}
+	if oldASN != nil {
+		oldASN.Close()
+	}
+	return nil
+}
+
+// changed reports whether any database file differs from what is loaded.
+func (s *Service) changed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for path, loaded := range s.files {
+		cur, err := statFile(path)
+		if err != nil {
+			// Mid-replacement; try again next tick.
+			continue
+		}
+		if !cur.modTime.Equal(loaded.modTime) || cur.size != loaded.size {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch polls the database files and reloads them when they change on disk,
+// e.g. after geoipupdate has fetched a new release. It blocks until ctx is
+// cancelled.
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.changed() {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				slog.Warn("GeoIP reload failed, keeping current databases", "error", err)
+				continue
+			}
+			slog.Info("GeoIP databases reloaded", "city", s.cityPath, "asn", s.asnPath)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestService_Changed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state, err := statFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Service{cityPath: path, files: map[string]fileState{path: state}}
+	if s.changed() {
+		t.Errorf("Expected unchanged file")
+	}
+
+	if err := os.WriteFile(path, []byte("version 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	if !s.changed() {
+		t.Errorf("Expected change to be detected")
+	}
+
+	// A file that's briefly missing (being replaced) isn't a change.
+	os.Remove(path)
+	if s.changed() {
+		t.Errorf("Missing file should not count as a change")
+	}
+}
+
+func TestService_ReloadKeepsOldOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.mmdb")
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(path, ""); err == nil {
+		t.Fatalf("Expected error opening invalid database")
+	}
+
+	s := &Service{cityPath: path}
+	if err := s.Reload(); err == nil {
+		t.Errorf("Expected reload error")
+	}
+	if s.db != nil {
+		t.Errorf("Reader should not be replaced on error")
+	}
+}
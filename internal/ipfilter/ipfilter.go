@@ -0,0 +1,152 @@
+// Package ipfilter rejects private, reserved and operator-denied addresses,
+// both when proxies are ingested and when the checker dials out.
+package ipfilter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"proxypool/internal/model"
+)
+
+// ErrDenied is returned by the dial guard for filtered destinations.
+var ErrDenied = errors.New("destination address denied")
+
+// reserved lists bogon ranges: private, loopback, link-local, CGNAT,
+// documentation, benchmarking, multicast and other special-purpose blocks.
+// IPv4-mapped IPv6 addresses are unmapped and judged by the IPv4 ranges.
+var reserved = mustParse(
+	// IPv4
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	// IPv6
+	"::/128",
+	"::1/128",
+	"64:ff9b:1::/48",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// Filter decides which addresses are acceptable. A nil *Filter allows everything.
+type Filter struct {
+	deny []netip.Prefix
+}
+
+// New returns a filter denying the reserved ranges plus any extra prefixes.
+func New(extra []netip.Prefix) *Filter {
+	deny := make([]netip.Prefix, 0, len(reserved)+len(extra))
+	deny = append(deny, reserved...)
+	deny = append(deny, extra...)
+	return &Filter{deny: deny}
+}
+
+// ParsePrefixes parses CIDRs; bare addresses are treated as single hosts.
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %w", s, err)
+			}
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", s, err)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+func mustParse(list ...string) []netip.Prefix {
+	out, err := ParsePrefixes(list)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// Allowed reports whether addr may be used.
+func (f *Filter) Allowed(addr netip.Addr) bool {
+	if f == nil {
+		return true
+	}
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range f.deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowedHost is like Allowed for a host string. Hostnames pass; they are
+// checked once resolved (and by the dial guard).
+func (f *Filter) AllowedHost(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return true
+	}
+	return f.Allowed(addr)
+}
+
+// Apply returns the proxies that pass the filter and how many were dropped.
+func (f *Filter) Apply(proxies []*model.Proxy) ([]*model.Proxy, int) {
+	if f == nil {
+		return proxies, 0
+	}
+	kept := proxies[:0:0]
+	for _, p := range proxies {
+		if f.AllowedHost(p.IP) {
+			kept = append(kept, p)
+		}
+	}
+	return kept, len(proxies) - len(kept)
+}
+
+// Control is a net.Dialer Control hook that refuses denied destinations.
+// It sees the resolved address, so DNS names can't be used to bypass it.
+func (f *Filter) Control(network, address string, _ syscall.RawConn) error {
+	if f == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDenied, err)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !f.Allowed(addr) {
+		return fmt.Errorf("%w: %s", ErrDenied, address)
+	}
+	return nil
+}
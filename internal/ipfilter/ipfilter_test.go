@@ -0,0 +1,86 @@
+package ipfilter
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"proxypool/internal/model"
+)
+
+func TestFilter_Allowed(t *testing.T) {
+	extra, err := ParsePrefixes([]string{"8.8.8.0/24", "9.9.9.9"})
+	if err != nil {
+		t.Fatalf("ParsePrefixes failed: %v", err)
+	}
+	f := New(extra)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"1.1.1.1", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"192.168.1.1", false},
+		{"172.20.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"192.0.2.10", false},
+		{"100.64.1.1", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"2001:db8::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:9.9.9.10", true},
+		{"8.8.8.8", false},
+		{"9.9.9.9", false},
+		{"9.9.9.10", true},
+	}
+
+	for _, tt := range tests {
+		if got := f.Allowed(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	var nilFilter *Filter
+	if !nilFilter.Allowed(netip.MustParseAddr("127.0.0.1")) {
+		t.Errorf("nil filter should allow everything")
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	f := New(nil)
+	proxies := []*model.Proxy{
+		{IP: "1.1.1.1", Port: 80},
+		{IP: "127.0.0.1", Port: 80},
+		{IP: "proxy.example.com", Port: 80},
+		{IP: "192.168.0.1", Port: 80},
+	}
+
+	kept, dropped := f.Apply(proxies)
+	if dropped != 2 || len(kept) != 2 {
+		t.Fatalf("Apply kept %d, dropped %d; want 2, 2", len(kept), dropped)
+	}
+	if kept[0].IP != "1.1.1.1" || kept[1].IP != "proxy.example.com" {
+		t.Errorf("Unexpected kept proxies: %v, %v", kept[0], kept[1])
+	}
+}
+
+func TestFilter_Control(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	d := net.Dialer{Control: New(nil).Control}
+	_, err = d.Dial("tcp", ln.Addr().String())
+	if !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected ErrDenied dialling loopback, got %v", err)
+	}
+}
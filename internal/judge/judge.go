@@ -0,0 +1,122 @@
+// Package judge implements a proxy judge: an HTTP endpoint that reports back
+// what a request looked like when it arrived, so the checker can learn a
+// proxy's exit IP and what headers it adds. It also serves a fixed payload
+// at /payload, which the checker uses to detect proxies that rewrite
+// content, and sized downloads at /bytes for measuring throughput. It must
+// be reachable from the internet for proxies to connect to it.
+package judge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response is what the judge returns for every request.
+type Response struct {
+	IP      string            `json:"ip"`      // Address the request came from (the proxy's exit IP).
+	Headers map[string]string `json:"headers"` // Request headers as received.
+}
+
+// Payload is the page served at /payload. It looks like an ordinary HTML
+// page so proxies that inject ads or scripts into pages act on it.
+var Payload = buildPayload()
+
+// PayloadHash is the hex SHA-256 of Payload.
+var PayloadHash = func() string {
+	sum := sha256.Sum256(Payload)
+	return hex.EncodeToString(sum[:])
+}()
+
+func buildPayload() []byte {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head><title>proxypool judge</title></head>\n<body>\n")
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "<p id=\"p%d\">Integrity check paragraph %d. Any change to this page means the proxy tampered with it.</p>\n", i, i)
+	}
+	b.WriteString("</body>\n</html>\n")
+	return []byte(b.String())
+}
+
+// Handler returns the judge's HTTP handler.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleEcho)
+	mux.HandleFunc("/payload", handlePayload)
+	mux.HandleFunc("/bytes", handleBytes)
+	return mux
+}
+
+// maxBytes caps the size /bytes will serve.
+const maxBytes = 64 << 20
+
+// handleBytes serves ?n= bytes (default 1 MiB) of incompressible-looking
+// filler for throughput measurements.
+func handleBytes(w http.ResponseWriter, r *http.Request) {
+	n := 1 << 20
+	if v := r.URL.Query().Get("n"); v != "" {
+		var err error
+		n, err = strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxBytes {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(n))
+	w.Header().Set("Cache-Control", "no-store, no-transform")
+
+	// A fixed pseudo-random block keeps compressing proxies from inflating the numbers.
+	for n > 0 {
+		chunk := fillerBlock[:min(n, len(fillerBlock))]
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		n -= len(chunk)
+	}
+}
+
+var fillerBlock = func() []byte {
+	b := make([]byte, 32<<10)
+	x := uint32(2463534242)
+	for i := range b {
+		// xorshift32
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		b[i] = byte(x)
+	}
+	return b
+}()
+
+func handlePayload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// no-transform asks well-behaved proxies not to recompress or rewrite.
+	w.Header().Set("Cache-Control", "no-store, no-transform")
+	w.Write(Payload)
+}
+
+func handleEcho(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	resp := Response{
+		IP:      host,
+		Headers: make(map[string]string, len(r.Header)),
+	}
+	for k, v := range r.Header {
+		resp.Headers[k] = strings.Join(v, ", ")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,71 @@
+package judge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Echo(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("Via", "1.1 squid")
+	rec := httptest.NewRecorder()
+
+	Handler().ServeHTTP(rec, req)
+
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if resp.IP != "203.0.113.7" {
+		t.Errorf("IP = %q, want %q", resp.IP, "203.0.113.7")
+	}
+	if resp.Headers["Via"] != "1.1 squid" {
+		t.Errorf("Via header = %q", resp.Headers["Via"])
+	}
+}
+
+func TestHandler_Payload(t *testing.T) {
+	req := httptest.NewRequest("GET", "/payload", nil)
+	rec := httptest.NewRecorder()
+
+	Handler().ServeHTTP(rec, req)
+
+	sum := sha256.Sum256(rec.Body.Bytes())
+	if got := hex.EncodeToString(sum[:]); got != PayloadHash {
+		t.Errorf("Payload hash = %s, want %s", got, PayloadHash)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestHandler_Bytes(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantCode int
+		wantLen  int
+	}{
+		{"", http.StatusOK, 1 << 20},
+		{"?n=100000", http.StatusOK, 100000},
+		{"?n=0", http.StatusOK, 0},
+		{"?n=-1", http.StatusBadRequest, 0},
+		{"?n=999999999", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/bytes"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("/bytes%s status = %d, want %d", tt.query, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantCode == http.StatusOK && rec.Body.Len() != tt.wantLen {
+			t.Errorf("/bytes%s length = %d, want %d", tt.query, rec.Body.Len(), tt.wantLen)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package leader elects one of several instances sharing a database to run
+// the tasks that must not run twice, using a Postgres advisory lock.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Elector campaigns for a session-level advisory lock on a connection of its
+// own. Whoever holds the lock leads; Postgres releases it when the session
+// ends, so a crashed leader is replaced within Interval.
+//
+// Session locks need a real session: connect directly or through a pooler in
+// session mode, never through a transaction pooler.
+type Elector struct {
+	connString string
+	key        int64
+
+	// Interval is how often a follower tries for the lock and the leader
+	// checks that its connection (and so the lock) is still there.
+	Interval time.Duration
+
+	leading atomic.Bool
+}
+
+// New returns an Elector for the lock called name on the database at
+// connString. Instances electing with the same name compete.
+func New(connString, name string) *Elector {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return &Elector{
+		connString: connString,
+		key:        int64(h.Sum64()),
+		Interval:   5 * time.Second,
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Lead blocks until ctx is cancelled. Whenever it holds the lock it runs fn
+// with a context that is cancelled when leadership is lost, and waits for fn
+// to return before campaigning again. A leader cut off from the database
+// notices within Interval, but the next one may take over before that, so fn
+// must tolerate a brief overlap.
+func (e *Elector) Lead(ctx context.Context, fn func(ctx context.Context)) {
+	for ctx.Err() == nil {
+		if err := e.campaign(ctx, fn); err != nil && ctx.Err() == nil {
+			slog.Error("Leader election failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(e.Interval):
+		}
+	}
+}
+
+// campaign connects and tries for the lock until it gets it, then leads
+// until the connection fails or ctx is cancelled.
+func (e *Elector) campaign(ctx context.Context, fn func(ctx context.Context)) error {
+	config, err := pgx.ParseConfig(e.connString)
+	if err != nil {
+		return err
+	}
+	config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+	for {
+		var locked bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&locked); err != nil {
+			return err
+		}
+		if locked {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+
+	slog.Info("Became leader")
+	e.leading.Store(true)
+	defer e.leading.Store(false)
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fn(leadCtx)
+	}()
+	defer wg.Wait()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Hand over only once fn is done.
+			cancel()
+			wg.Wait()
+			unlockCtx, unlockCancel := context.WithTimeout(context.Background(), e.Interval)
+			defer unlockCancel()
+			if _, err := conn.Exec(unlockCtx, "SELECT pg_advisory_unlock($1)", e.key); err != nil {
+				slog.Error("Releasing leadership failed", "error", err)
+			}
+			return nil
+		case <-ticker.C:
+			pingCtx, pingCancel := context.WithTimeout(ctx, e.Interval)
+			err := conn.Ping(pingCtx)
+			pingCancel()
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("lost leadership: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/joho/godotenv"
+)
+
+func TestElector_Lead(t *testing.T) {
+	_ = godotenv.Load("../../.env")
+	dbURL := os.Getenv("DIRECT_URL")
+	if dbURL == "" {
+		t.Skip("DIRECT_URL not set, skipping integration test")
+	}
+	conn, err := pgx.Connect(context.Background(), dbURL)
+	if err != nil {
+		t.Fatalf("Failed to connect to DB: %v", err)
+	}
+	conn.Close(context.Background())
+
+	name := fmt.Sprintf("leader-test-%d", time.Now().UnixNano())
+	first, second := New(dbURL, name), New(dbURL, name)
+	first.Interval, second.Interval = 100*time.Millisecond, 100*time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	firstCtx, stopFirst := context.WithCancel(ctx)
+	firstLeads := make(chan struct{})
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		first.Lead(firstCtx, func(ctx context.Context) {
+			close(firstLeads)
+			<-ctx.Done()
+		})
+	}()
+	select {
+	case <-firstLeads:
+	case <-ctx.Done():
+		t.Fatal("First elector never led")
+	}
+
+	secondLeads := make(chan struct{})
+	go second.Lead(ctx, func(ctx context.Context) {
+		close(secondLeads)
+		<-ctx.Done()
+	})
+	select {
+	case <-secondLeads:
+		t.Fatal("Second elector led while the first held the lock")
+	case <-time.After(time.Second):
+	}
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("IsLeader = %v, %v; want true, false", first.IsLeader(), second.IsLeader())
+	}
+
+	stopFirst()
+	<-firstDone
+	select {
+	case <-secondLeads:
+	case <-ctx.Done():
+		t.Fatal("Second elector didn't take over")
+	}
+	if first.IsLeader() {
+		t.Error("First elector still claims to lead")
+	}
+}
@@ -0,0 +1,46 @@
+// Package metrics holds the process-wide counters. They are published with
+// expvar and served by the API at /metrics.
+package metrics
+
+import "expvar"
+
+var (
+	// IngestSaved counts scraped proxies handed to storage, by source.
+	IngestSaved = expvar.NewMap("ingest_saved")
+	// IngestDropped counts scraped proxies rejected by the ingest filter, by source.
+	IngestDropped = expvar.NewMap("ingest_dropped")
+
+	// PrecheckPassed counts proxies that accepted a TCP connection in the pre-check.
+	PrecheckPassed = expvar.NewInt("precheck_passed")
+	// PrecheckFailed counts proxies dropped by the pre-check, by failure class (refused, dial_timeout, ...).
+	PrecheckFailed = expvar.NewMap("precheck_failed")
+
+	// RateLimited counts checks put off by a rate limit, by kind (target, subnet, asn).
+	RateLimited = expvar.NewMap("rate_limited")
+
+	// LaneDispatched counts proxies queued for a check, by check lane.
+	LaneDispatched = expvar.NewMap("lane_dispatched")
+
+	// CheckWorkers is the number of check workers currently allowed to run.
+	CheckWorkers = expvar.NewInt("check_workers")
+
+	// ChecksAlive counts full checks that passed.
+	ChecksAlive = expvar.NewInt("checks_alive")
+	// CheckFailures counts full checks that failed, by failure class (refused, dial_timeout, ...).
+	CheckFailures = expvar.NewMap("check_failures")
+	// CheckRetries counts re-runs of failed checks.
+	CheckRetries = expvar.NewInt("check_retries")
+	// ChecksRecovered counts checks that failed at first but passed on a retry.
+	ChecksRecovered = expvar.NewInt("checks_recovered")
+	// ProxiesPromoted counts new proxies that passed enough checks in a row to be served.
+	ProxiesPromoted = expvar.NewInt("proxies_promoted")
+
+	// Leader is 1 while this instance runs the singleton tasks (scraping,
+	// profiles, resolving, pruning), 0 otherwise.
+	Leader = expvar.NewInt("leader")
+
+	// ResolutionChanges counts hostname proxies that moved to a new address.
+	ResolutionChanges = expvar.NewInt("resolution_changes")
+	// ResolutionFailures counts hostname lookups that failed or found no allowed address.
+	ResolutionFailures = expvar.NewInt("resolution_failures")
+)
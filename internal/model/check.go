@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Timings breaks a request through a proxy into its phases, in milliseconds.
+// Phases that didn't happen (e.g. TLS for plain HTTP) are 0.
+type Timings struct {
+	ConnectMS   int `json:"connect_ms" db:"connect_ms"`     // TCP connect to the proxy
+	HandshakeMS int `json:"handshake_ms" db:"handshake_ms"` // Proxy protocol: SOCKS negotiation or CONNECT
+	TLSMS       int `json:"tls_ms" db:"tls_ms"`             // TLS handshake with the target (or a TLS proxy)
+	TTFBMS      int `json:"ttfb_ms" db:"ttfb_ms"`           // Request written to first response byte
+	TotalMS     int `json:"total_ms" db:"total_ms"`         // Whole request, including reading the response
+}
+
+// CheckRecord is one entry of a proxy's check history.
+type CheckRecord struct {
+	ProxyID         int64     `json:"proxy_id" db:"proxy_id"`
+	CheckedAt       time.Time `json:"checked_at" db:"checked_at"`
+	Alive           bool      `json:"alive" db:"alive"`
+	FailedValidator string    `json:"failed_validator,omitempty" db:"failed_validator"`
+	Failure         string    `json:"failure,omitempty" db:"failure"` // Failure class, see checker.Classify
+	Attempts        int       `json:"attempts" db:"attempts"`         // Runs of the pipeline, including retries
+	Timings
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// ProfileResult is the outcome of running a named check profile against a proxy.
+type ProfileResult struct {
+	ProxyID   int64     `json:"proxy_id" db:"proxy_id"`
+	Profile   string    `json:"profile" db:"profile"`
+	Passed    bool      `json:"passed" db:"passed"`
+	LatencyMS int       `json:"latency_ms" db:"latency_ms"` // Total time for all of the profile's targets
+	Error     string    `json:"error,omitempty" db:"error"` // Why the profile failed
+	CheckedAt time.Time `json:"checked_at" db:"checked_at"`
+}
@@ -1,7 +1,10 @@
 package model
 
 import (
-	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -20,22 +23,58 @@ const (
 	AnonymityElite       = "elite"
 )
 
+// Network types, derived from the proxy's ASN.
+const (
+	NetworkHosting     = "hosting"
+	NetworkResidential = "residential"
+	NetworkMobile      = "mobile"
+)
+
 // Proxy represents a proxy server entity.
 type Proxy struct {
-	ID            int64      `json:"id" db:"id"`
-	IP            string     `json:"ip" db:"ip"`
-	Port          int        `json:"port" db:"port"`
-	Protocol      string     `json:"protocol" db:"protocol"`
-	Country       string     `json:"country" db:"country"`
-	Anonymity     string     `json:"anonymity" db:"anonymity"`
-	LatencyMS     int        `json:"latency_ms" db:"latency_ms"` // Latency in milliseconds
-	LastCheckedAt *time.Time `json:"last_checked_at" db:"last_checked_at"`
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-}
-
-// Address returns the "ip:port" string.
+	ID              int64      `json:"id" db:"id"`
+	IP              string     `json:"ip" db:"ip"`               // Entry address; for hostname proxies, the current resolution ("" until resolved)
+	Host            string     `json:"host,omitempty" db:"host"` // DNS name the proxy is listed under, if any
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+	Port            int        `json:"port" db:"port"`
+	Protocol        string     `json:"protocol" db:"protocol"`
+	Username        string     `json:"username,omitempty" db:"-"`      // Stored encrypted
+	Password        string     `json:"-" db:"-"`                       // Stored encrypted, never serialised
+	AuthHash        []byte     `json:"-" db:"auth_hash"`               // Fingerprint of the stored credentials, as read
+	ExitIP          string     `json:"exit_ip,omitempty" db:"exit_ip"` // Address traffic leaves from, as seen by the judge
+	Country         string     `json:"country" db:"country"`
+	City            string     `json:"city" db:"city"`
+	Region          string     `json:"region" db:"region"`
+	Latitude        float64    `json:"latitude" db:"latitude"`
+	Longitude       float64    `json:"longitude" db:"longitude"`
+	ASN             int        `json:"asn" db:"asn"`
+	ASNOrg          string     `json:"asn_org" db:"asn_org"`
+	NetworkType     string     `json:"network_type" db:"network_type"` // hosting, residential or mobile
+	Anonymity       string     `json:"anonymity" db:"anonymity"`
+	SupportsHTTPS   *bool      `json:"supports_https" db:"supports_https"` // CONNECT + TLS works; nil if never tested
+	SupportsIPv6    *bool      `json:"supports_ipv6" db:"supports_ipv6"`   // Reaches IPv6 targets; nil if never tested
+	Tampered        bool       `json:"tampered" db:"tampered"`             // Caught modifying content or forging certificates
+	TamperReason    string     `json:"tamper_reason,omitempty" db:"tamper_reason"`
+	LatencyMS       int        `json:"latency_ms" db:"latency_ms"`           // Latency in milliseconds
+	RegionLatencyMS int        `json:"region_latency_ms,omitempty" db:"-"`   // Latency from the check region listed for; 0 otherwise
+	ThroughputKBps  int        `json:"throughput_kbps" db:"throughput_kbps"` // Measured download rate; 0 if never measured
+	LastCheckedAt   *time.Time `json:"last_checked_at" db:"last_checked_at"`
+	LastAliveAt     *time.Time `json:"last_alive_at,omitempty" db:"last_alive_at"` // When a check last passed
+	LastFailure     string     `json:"last_failure,omitempty" db:"last_failure"`   // Why the latest check failed; empty if it passed
+	SuccessStreak   int        `json:"success_streak" db:"success_streak"`         // Consecutive passed checks
+	PromotedAt      *time.Time `json:"promoted_at,omitempty" db:"promoted_at"`     // When the streak first got long enough to serve; nil if never
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Address returns the "ip:port" string, with IPv6 addresses in brackets
+// ("[2001:db8::1]:8080"). Hostname proxies that were never resolved give
+// "host:port".
 func (p *Proxy) Address() string {
-	return fmt.Sprintf("%s:%d", p.IP, p.Port)
+	host := p.IP
+	if host == "" {
+		host = p.Host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p.Port))
 }
 
 // URL returns the full URL representation (e.g., "http://ip:port").
@@ -45,5 +84,54 @@ func (p *Proxy) URL() string {
 	if proto == "" {
 		proto = ProtocolHTTP
 	}
-	return fmt.Sprintf("%s://%s:%d", proto, p.IP, p.Port)
+	return proto + "://" + p.Address()
+}
+
+// IsHostname reports whether the proxy is listed under a DNS name.
+func (p *Proxy) IsHostname() bool {
+	return p.Host != ""
+}
+
+// IsIPv6 reports whether the proxy's entry address is IPv6.
+func (p *Proxy) IsIPv6() bool {
+	addr, err := netip.ParseAddr(p.IP)
+	return err == nil && !addr.Unmap().Is4()
+}
+
+// GeoIP returns the address to geolocate: the observed exit IP if known,
+// otherwise the entry IP.
+func (p *Proxy) GeoIP() string {
+	if p.ExitIP != "" {
+		return p.ExitIP
+	}
+	return p.IP
+}
+
+// MultiHop reports whether traffic leaves from a different address than the
+// one we connect to (chained or load-balanced proxies).
+func (p *Proxy) MultiHop() bool {
+	return p.ExitIP != "" && p.ExitIP != p.IP
+}
+
+// HasAuth reports whether the proxy requires credentials.
+func (p *Proxy) HasAuth() bool {
+	return p.Username != ""
+}
+
+// AuthURL is like URL but includes the credentials
+// (e.g. "socks5://user:pass@ip:port"). Only hand it to trusted consumers.
+func (p *Proxy) AuthURL() string {
+	if !p.HasAuth() {
+		return p.URL()
+	}
+	proto := p.Protocol
+	if proto == "" {
+		proto = ProtocolHTTP
+	}
+	u := url.URL{
+		Scheme: proto,
+		User:   url.UserPassword(p.Username, p.Password),
+		Host:   p.Address(),
+	}
+	return u.String()
 }
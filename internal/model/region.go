@@ -0,0 +1,24 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidRegion reports whether name can name a check region, e.g. "eu-west".
+func ValidRegion(name string) bool {
+	return regionPattern.MatchString(name)
+}
+
+// RegionResult is the outcome of checking a proxy from a region, as
+// reported by a checker agent there.
+type RegionResult struct {
+	ProxyID   int64     `json:"proxy_id" db:"proxy_id"`
+	Region    string    `json:"region" db:"region"`
+	Alive     bool      `json:"alive" db:"alive"`
+	LatencyMS int       `json:"latency_ms" db:"latency_ms"`
+	Failure   string    `json:"failure,omitempty" db:"failure"` // checker.Failure* class if not alive
+	CheckedAt time.Time `json:"checked_at" db:"checked_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Resolution is the result of resolving a hostname proxy.
+type Resolution struct {
+	ProxyID    int64     `json:"proxy_id" db:"proxy_id"`
+	IP         string    `json:"ip" db:"ip"`         // New address; empty if the lookup failed
+	PreviousIP string    `json:"previous_ip" db:"-"` // Address before this resolution; empty if never resolved
+	ResolvedAt time.Time `json:"resolved_at" db:"resolved_at"`
+}
+
+// Changed reports whether the proxy now has a different address.
+func (r *Resolution) Changed() bool {
+	return r.IP != "" && r.IP != r.PreviousIP
+}
@@ -0,0 +1,125 @@
+// Package ratelimit provides keyed token buckets: one bucket per key (a
+// target host, a subnet, an ASN), created on first use and dropped once
+// idle.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped.
+const sweepInterval = time.Minute
+
+// Limiter allows rate events per second per key, with bursts of up to burst.
+// A nil *Limiter allows everything.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter for rate events per second per key. burst <= 0
+// means one second's worth (at least 1). A rate <= 0 returns nil, which
+// doesn't limit.
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Reserve takes a token for key if one is available within maxWait and
+// returns how long the caller has to wait before acting on it. If the wait
+// would be longer, nothing is taken and ok is false.
+func (l *Limiter) Reserve(key string, maxWait time.Duration) (wait time.Duration, ok bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b := l.refill(key, now)
+
+	tokens := b.tokens - 1
+	if tokens < 0 {
+		wait = time.Duration(-tokens / l.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens = tokens
+	return wait, true
+}
+
+// Cancel returns a token taken by Reserve, e.g. when another limit refused
+// the same action.
+func (l *Limiter) Cancel(key string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = min(b.tokens+1, l.burst)
+	}
+}
+
+// Len returns the number of buckets in use.
+func (l *Limiter) Len() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// refill returns key's bucket topped up to now. Must hold mu.
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+		return b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*l.rate, l.burst)
+		b.last = now
+	}
+	return b
+}
+
+// sweep drops buckets that have refilled completely; they behave exactly
+// like new ones. Must hold mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests move time by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(rate float64, burst int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := New(rate, burst)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLimiter_Reserve(t *testing.T) {
+	l, clock := newTestLimiter(2, 2)
+
+	// The burst is available straight away, per key.
+	for i := 0; i < 2; i++ {
+		if wait, ok := l.Reserve("a", 0); !ok || wait != 0 {
+			t.Fatalf("Reserve %d = %v, %v; want immediate", i, wait, ok)
+		}
+	}
+	if _, ok := l.Reserve("b", 0); !ok {
+		t.Fatal("Keys should have separate buckets")
+	}
+
+	// Then one token every 500ms.
+	if wait, ok := l.Reserve("a", 0); ok {
+		t.Fatalf("Reserve past the burst = %v, %v; want refused", wait, ok)
+	}
+	if wait, ok := l.Reserve("a", time.Second); !ok || wait != 500*time.Millisecond {
+		t.Fatalf("Reserve with maxWait = %v, %v; want 500ms", wait, ok)
+	}
+	if wait, ok := l.Reserve("a", time.Second); !ok || wait != time.Second {
+		t.Fatalf("Second queued reserve = %v, %v; want 1s", wait, ok)
+	}
+
+	clock.advance(2 * time.Second)
+	if wait, ok := l.Reserve("a", 0); !ok || wait != 0 {
+		t.Fatalf("Reserve after refill = %v, %v; want immediate", wait, ok)
+	}
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	l, _ := newTestLimiter(1, 1)
+
+	if _, ok := l.Reserve("a", 0); !ok {
+		t.Fatal("First reserve refused")
+	}
+	l.Cancel("a")
+	if _, ok := l.Reserve("a", 0); !ok {
+		t.Fatal("Cancelled token wasn't returned")
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	l, clock := newTestLimiter(10, 0)
+
+	l.Reserve("a", 0)
+	l.Reserve("b", 0)
+	clock.advance(2 * sweepInterval)
+	l.Reserve("c", 0)
+	if n := l.Len(); n != 1 {
+		t.Errorf("Expected idle buckets to be dropped, have %d", n)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	l := New(0, 0)
+	if l != nil {
+		t.Fatal("A zero rate should give a nil limiter")
+	}
+	for i := 0; i < 100; i++ {
+		if _, ok := l.Reserve("a", 0); !ok {
+			t.Fatal("A nil limiter should allow everything")
+		}
+	}
+}
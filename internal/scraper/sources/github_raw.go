@@ -1,14 +1,10 @@
 package sources
 
 import (
-	"bufio"
 	"context"
-	"fmt"
-	"net/http"
-	"strconv"
-	"strings"
 
 	"proxypool/internal/model"
+	"proxypool/internal/scraper/parser"
 )
 
 // GithubRawSource scrapes proxies from a raw text file URL.
@@ -31,49 +27,26 @@ func (s *GithubRawSource) Name() string {
 }
 
 func (s *GithubRawSource) Fetch(ctx context.Context) ([]*model.Proxy, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", s.url, nil)
+	resp, err := get(ctx, s.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("fetch failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	entries, rejected, err := parser.ParseList(resp.Body, parseOptions)
+	if err != nil {
+		return nil, err
 	}
+	logRejections(s.name, rejected)
 
 	var proxies []*model.Proxy
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		host, portStr, found := strings.Cut(line, ":")
-		if !found {
-			continue
+	for _, e := range entries {
+		p := e.Proxy(s.protocolOverride)
+		// An explicit override beats whatever scheme the line carried.
+		if s.protocolOverride != "" {
+			p.Protocol = s.protocolOverride
 		}
-
-		port, err := strconv.Atoi(portStr)
-		if err != nil {
-			continue
-		}
-
-		proxies = append(proxies, &model.Proxy{
-			IP:       host,
-			Port:     port,
-			Protocol: s.protocolOverride,
-		})
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan error: %w", err)
+		proxies = append(proxies, p)
 	}
 
 	return proxies, nil
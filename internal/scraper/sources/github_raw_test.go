@@ -36,3 +36,36 @@ func TestGithubRawSource_Fetch(t *testing.T) {
 		t.Errorf("Unexpected proxy 1: %v", proxies[1])
 	}
 }
+
+func TestGithubRawSource_FetchFormats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "socks5://1.1.1.1:1080")
+		fmt.Fprintln(w, "2.2.2.2:3128 # trailing comment")
+		fmt.Fprintln(w, "[2001:db8::1]:8080")
+		fmt.Fprintln(w, "3.3.3.3:0")
+		fmt.Fprintln(w, "4.4.4.4:99999")
+		fmt.Fprintln(w, "proxy.example.com:8080")
+	}))
+	defer ts.Close()
+
+	proxies, err := NewGithubRawSource("test_source", ts.URL, "socks5").Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if len(proxies) != 4 {
+		t.Fatalf("Expected 4 proxies, got %d", len(proxies))
+	}
+	if proxies[0].IP != "1.1.1.1" || proxies[0].Port != 1080 || proxies[0].Protocol != "socks5" {
+		t.Errorf("Unexpected proxy 0: %v", proxies[0])
+	}
+	if proxies[1].IP != "2.2.2.2" || proxies[1].Port != 3128 {
+		t.Errorf("Unexpected proxy 1: %v", proxies[1])
+	}
+	if proxies[2].IP != "2001:db8::1" || proxies[2].Port != 8080 {
+		t.Errorf("Unexpected proxy 2: %v", proxies[2])
+	}
+	if proxies[3].Host != "proxy.example.com" || proxies[3].IP != "" || proxies[3].Port != 8080 {
+		t.Errorf("Unexpected proxy 3: %v", proxies[3])
+	}
+}
@@ -0,0 +1,401 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"proxypool/internal/model"
+	"proxypool/internal/scraper/parser"
+)
+
+// DefaultHTMLPattern matches "ip:port" pairs anywhere in a page.
+var DefaultHTMLPattern = regexp.MustCompile(`(\d{1,3}(?:\.\d{1,3}){3})\s*:\s*(\d{1,5})`)
+
+// HTMLConfig controls how proxies are extracted from an HTML page.
+//
+// If Table is set, proxies are read from the cells of the first table matching
+// that selector. Otherwise Pattern (or DefaultHTMLPattern) is run over the raw
+// page. Patterns may use named groups "ip", "port" and "protocol"; without
+// names, group 1 is the host and group 2 the port.
+type HTMLConfig struct {
+	Protocol string         // Protocol used when the page doesn't say.
+	Pattern  *regexp.Regexp // Regex extraction.
+
+	// Table extraction. Table is a simple selector ("table", "#list",
+	// "table.proxies"). Columns are cell selectors such as "td:nth-child(1)"
+	// or "td.port". ProtocolColumn is optional.
+	Table          string
+	IPColumn       string
+	PortColumn     string
+	ProtocolColumn string
+
+	// NextPage finds the link to the next page; group 1 is the href.
+	// At most MaxPages pages are fetched (default 1).
+	NextPage *regexp.Regexp
+	MaxPages int
+}
+
+// HTMLSource scrapes proxies from HTML pages, such as free-proxy sites that
+// only publish tables.
+type HTMLSource struct {
+	name string
+	url  string
+	cfg  HTMLConfig
+}
+
+func NewHTMLSource(name, url string, cfg HTMLConfig) *HTMLSource {
+	if cfg.Pattern == nil {
+		cfg.Pattern = DefaultHTMLPattern
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = 1
+	}
+	return &HTMLSource{
+		name: name,
+		url:  url,
+		cfg:  cfg,
+	}
+}
+
+func (s *HTMLSource) Name() string {
+	return s.name
+}
+
+func (s *HTMLSource) Fetch(ctx context.Context) ([]*model.Proxy, error) {
+	var proxies []*model.Proxy
+	visited := make(map[string]bool)
+
+	pageURL := s.url
+	for page := 0; page < s.cfg.MaxPages && pageURL != "" && !visited[pageURL]; page++ {
+		visited[pageURL] = true
+
+		body, err := getBody(ctx, pageURL)
+		if err != nil {
+			// A broken later page shouldn't throw away what we already have.
+			if page > 0 {
+				break
+			}
+			return nil, err
+		}
+
+		found, err := s.extract(string(body))
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, found...)
+
+		pageURL = s.nextPage(pageURL, string(body))
+	}
+
+	return proxies, nil
+}
+
+// nextPage returns the absolute URL of the next page, or "" if there is none.
+func (s *HTMLSource) nextPage(current, body string) string {
+	if s.cfg.NextPage == nil {
+		return ""
+	}
+	m := s.cfg.NextPage.FindStringSubmatch(body)
+	if len(m) < 2 || m[1] == "" {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(html.UnescapeString(m[1]))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (s *HTMLSource) extract(body string) ([]*model.Proxy, error) {
+	if s.cfg.Table != "" {
+		return s.extractTable(body)
+	}
+	return s.extractPattern(body), nil
+}
+
+func (s *HTMLSource) extractPattern(body string) []*model.Proxy {
+	re := s.cfg.Pattern
+	hostIdx, portIdx, protoIdx := re.SubexpIndex("ip"), re.SubexpIndex("port"), re.SubexpIndex("protocol")
+	if hostIdx < 0 {
+		hostIdx = re.SubexpIndex("host")
+	}
+	if hostIdx < 0 || portIdx < 0 {
+		hostIdx, portIdx = 1, 2
+	}
+
+	var c collector
+	for i, m := range re.FindAllStringSubmatch(body, -1) {
+		if len(m) <= hostIdx || len(m) <= portIdx {
+			continue
+		}
+		proto := ""
+		if protoIdx >= 0 {
+			proto = m[protoIdx]
+		}
+		p, err := s.newProxy(m[hostIdx], m[portIdx], proto)
+		c.add(p, err, i+1, m[0])
+	}
+	logRejections(s.name, c.rejected)
+	return c.proxies
+}
+
+func (s *HTMLSource) extractTable(body string) ([]*model.Proxy, error) {
+	tableSel, err := parseSelector(s.cfg.Table)
+	if err != nil {
+		return nil, fmt.Errorf("table selector: %w", err)
+	}
+	ipSel, err := parseSelector(s.cfg.IPColumn)
+	if err != nil {
+		return nil, fmt.Errorf("ip column: %w", err)
+	}
+	portSel, err := parseSelector(s.cfg.PortColumn)
+	if err != nil {
+		return nil, fmt.Errorf("port column: %w", err)
+	}
+	var protoSel *selector
+	if s.cfg.ProtocolColumn != "" {
+		protoSel, err = parseSelector(s.cfg.ProtocolColumn)
+		if err != nil {
+			return nil, fmt.Errorf("protocol column: %w", err)
+		}
+	}
+
+	var c collector
+	for i, row := range tableRows(body, tableSel) {
+		host, ok := row.find(ipSel)
+		if !ok {
+			continue
+		}
+		port, ok := row.find(portSel)
+		if !ok {
+			continue
+		}
+		proto := ""
+		if protoSel != nil {
+			proto, _ = row.find(protoSel)
+		}
+		p, err := s.newProxy(host, port, proto)
+		c.add(p, err, i+1, host+" "+port)
+	}
+	logRejections(s.name, c.rejected)
+	return c.proxies, nil
+}
+
+func (s *HTMLSource) newProxy(host, port, proto string) (*model.Proxy, error) {
+	e, err := parser.ParseParts(host, port, parseOptions)
+	if err != nil {
+		return nil, err
+	}
+	e.Scheme, _ = parser.Protocol(proto)
+	return e.Proxy(s.cfg.Protocol), nil
+}
+
+// collector gathers extracted proxies and rejected candidates.
+type collector struct {
+	proxies  []*model.Proxy
+	rejected []parser.Rejection
+}
+
+func (c *collector) add(p *model.Proxy, err error, n int, text string) {
+	if err != nil {
+		c.rejected = append(c.rejected, parser.Rejection{Line: n, Text: text, Err: err})
+		return
+	}
+	c.proxies = append(c.proxies, p)
+}
+
+// ---- Minimal HTML table reader ----
+//
+// Free-proxy sites are simple enough that a tag scanner is sufficient; we don't
+// need a full HTML5 parser.
+
+var (
+	tagRe  = regexp.MustCompile(`(?s)<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	attrRe = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	selRe  = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*)?((?:[#.][-_a-zA-Z0-9]+)*)(?::nth-child\((\d+)\))?$`)
+	partRe = regexp.MustCompile(`[#.][^#.]+`)
+	wsRe   = regexp.MustCompile(`\s+`)
+)
+
+// selector is a tiny subset of CSS: tag, #id, .class and :nth-child(n).
+type selector struct {
+	tag     string
+	id      string
+	classes []string
+	nth     int // 1-based, 0 means any
+}
+
+func parseSelector(s string) (*selector, error) {
+	s = strings.TrimSpace(s)
+	m := selRe.FindStringSubmatch(s)
+	if s == "" || m == nil {
+		return nil, fmt.Errorf("unsupported selector %q", s)
+	}
+	sel := &selector{tag: strings.ToLower(m[1])}
+	for _, part := range partRe.FindAllString(m[2], -1) {
+		if part[0] == '#' {
+			sel.id = part[1:]
+		} else {
+			sel.classes = append(sel.classes, part[1:])
+		}
+	}
+	if m[3] != "" {
+		sel.nth, _ = strconv.Atoi(m[3])
+	}
+	return sel, nil
+}
+
+// matches reports whether an element with the given tag and attributes,
+// at 1-based position pos among its siblings, matches the selector.
+func (sel *selector) matches(tag string, attrs map[string]string, pos int) bool {
+	if sel.tag != "" && sel.tag != tag {
+		return false
+	}
+	if sel.id != "" && attrs["id"] != sel.id {
+		return false
+	}
+	have := strings.Fields(attrs["class"])
+	for _, want := range sel.classes {
+		if !slices.Contains(have, want) {
+			return false
+		}
+	}
+	return sel.nth == 0 || sel.nth == pos
+}
+
+type tableCell struct {
+	tag   string
+	attrs map[string]string
+	text  string
+}
+
+type tableRow []tableCell
+
+// find returns the text of the first cell matching sel.
+func (r tableRow) find(sel *selector) (string, bool) {
+	for i, c := range r {
+		if sel.matches(c.tag, c.attrs, i+1) {
+			return c.text, true
+		}
+	}
+	return "", false
+}
+
+// tableRows returns the rows of the first table matching sel.
+// Nested tables are treated as part of the enclosing cell's text.
+func tableRows(body string, sel *selector) []tableRow {
+	var (
+		rows    []tableRow
+		row     tableRow
+		cell    *tableCell
+		text    strings.Builder
+		depth   int // nesting depth inside the matched table
+		skipEnd string
+	)
+
+	last := 0
+	for _, loc := range tagRe.FindAllStringSubmatchIndex(body, -1) {
+		if skipEnd != "" {
+			// Inside <script>/<style>: ignore everything until it closes.
+			if body[loc[2]:loc[3]] == "/" && strings.EqualFold(body[loc[4]:loc[5]], skipEnd) {
+				skipEnd = ""
+				last = loc[1]
+			}
+			continue
+		}
+
+		if cell != nil {
+			text.WriteString(body[last:loc[0]])
+		}
+		last = loc[1]
+
+		closing := body[loc[2]:loc[3]] == "/"
+		tag := strings.ToLower(body[loc[4]:loc[5]])
+		attrs := body[loc[6]:loc[7]]
+
+		if !closing && (tag == "script" || tag == "style") {
+			skipEnd = tag
+			continue
+		}
+
+		if depth == 0 {
+			if tag == "table" && !closing && sel.matches(tag, parseAttrs(attrs), 0) {
+				depth = 1
+			}
+			continue
+		}
+
+		switch {
+		case tag == "table" && !closing:
+			depth++
+		case tag == "table" && closing:
+			depth--
+			if depth == 0 {
+				if cell != nil {
+					row = append(row, finishCell(cell, &text))
+					cell = nil
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+				return rows
+			}
+		case depth > 1:
+			// Markup of a nested table only contributes text.
+		case tag == "tr":
+			if cell != nil {
+				row = append(row, finishCell(cell, &text))
+				cell = nil
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+			row = nil
+		case tag == "td" || tag == "th":
+			if cell != nil {
+				row = append(row, finishCell(cell, &text))
+				cell = nil
+			}
+			if !closing {
+				cell = &tableCell{tag: tag, attrs: parseAttrs(attrs)}
+			}
+		case tag == "br" || tag == "p" || tag == "div":
+			if cell != nil {
+				text.WriteByte(' ')
+			}
+		}
+	}
+
+	// Unterminated table: return what we collected.
+	if cell != nil {
+		row = append(row, finishCell(cell, &text))
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func finishCell(c *tableCell, text *strings.Builder) tableCell {
+	c.text = strings.TrimSpace(wsRe.ReplaceAllString(html.UnescapeString(text.String()), " "))
+	text.Reset()
+	return *c
+}
+
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
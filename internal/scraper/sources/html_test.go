@@ -0,0 +1,122 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestHTMLSource_FetchTable(t *testing.T) {
+	// Mock Server with two pages linked by a "Next" anchor
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprint(w, `<html><body>
+				<table class="nav"><tr><td>9.9.9.9</td><td>1</td></tr></table>
+				<table id="proxylisttable" class="table table-striped">
+					<thead><tr><th>IP Address</th><th>Port</th><th>Type</th></tr></thead>
+					<tbody>
+						<tr><td>1.1.1.1</td><td>8080</td><td>HTTP</td></tr>
+						<tr><td><span>2.2.2.2</span></td><td> 1080 </td><td>socks5</td></tr>
+						<tr><td>broken</td><td>port</td><td>http</td></tr>
+					</tbody>
+				</table>
+				<a class="next" href="?page=2&amp;sort=1">Next</a>
+			</body></html>`)
+		case "2":
+			fmt.Fprint(w, `<table id="proxylisttable">
+				<tr><td>3.3.3.3</td><td>3128</td><td>unknown</td></tr>
+			</table>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	source := NewHTMLSource("test_html", ts.URL, HTMLConfig{
+		Protocol:       "http",
+		Table:          "table#proxylisttable",
+		IPColumn:       "td:nth-child(1)",
+		PortColumn:     "td:nth-child(2)",
+		ProtocolColumn: "td:nth-child(3)",
+		NextPage:       regexp.MustCompile(`<a class="next" href="([^"]+)"`),
+		MaxPages:       5,
+	})
+
+	proxies, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	want := []struct {
+		ip       string
+		port     int
+		protocol string
+	}{
+		{"1.1.1.1", 8080, "http"},
+		{"2.2.2.2", 1080, "socks5"},
+		{"3.3.3.3", 3128, "http"},
+	}
+	if len(proxies) != len(want) {
+		t.Fatalf("Expected %d proxies, got %d", len(want), len(proxies))
+	}
+	for i, w := range want {
+		p := proxies[i]
+		if p.IP != w.ip || p.Port != w.port || p.Protocol != w.protocol {
+			t.Errorf("Unexpected proxy %d: %+v", i, p)
+		}
+	}
+}
+
+func TestHTMLSource_FetchPattern(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<pre>1.1.1.1:8080
+		2.2.2.2 : 9000</pre>
+		<div data-host="4.4.4.4" data-port="3128" data-type="socks4"></div>`)
+	}))
+	defer ts.Close()
+
+	// Default pattern
+	proxies, err := NewHTMLSource("test_default", ts.URL, HTMLConfig{Protocol: "http"}).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(proxies) != 2 {
+		t.Fatalf("Expected 2 proxies, got %d", len(proxies))
+	}
+	if proxies[1].IP != "2.2.2.2" || proxies[1].Port != 9000 {
+		t.Errorf("Unexpected proxy 1: %v", proxies[1])
+	}
+
+	// Named groups
+	source := NewHTMLSource("test_named", ts.URL, HTMLConfig{
+		Protocol: "http",
+		Pattern:  regexp.MustCompile(`data-host="(?P<ip>[^"]+)" data-port="(?P<port>\d+)" data-type="(?P<protocol>\w+)"`),
+	})
+	proxies, err = source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(proxies) != 1 || proxies[0].IP != "4.4.4.4" || proxies[0].Port != 3128 || proxies[0].Protocol != "socks4" {
+		t.Errorf("Unexpected proxies: %v", proxies)
+	}
+}
+
+func TestHTMLSource_BadSelector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<table></table>`)
+	}))
+	defer ts.Close()
+
+	source := NewHTMLSource("test_bad", ts.URL, HTMLConfig{
+		Table:      "table > tr",
+		IPColumn:   "td:nth-child(1)",
+		PortColumn: "td:nth-child(2)",
+	})
+	if _, err := source.Fetch(context.Background()); err == nil {
+		t.Errorf("Expected error for unsupported selector")
+	}
+}
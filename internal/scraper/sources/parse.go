@@ -0,0 +1,24 @@
+package sources
+
+import (
+	"log/slog"
+
+	"proxypool/internal/scraper/parser"
+)
+
+// parseOptions is what every source accepts from the shared parser.
+// Hostnames are stored and resolved periodically by the engine.
+var parseOptions = parser.Options{AllowHostnames: true}
+
+// logRejections summarises rejected lines by reason.
+func logRejections(source string, rejected []parser.Rejection) {
+	if len(rejected) == 0 {
+		return
+	}
+	byReason := make(map[string]int)
+	for _, r := range rejected {
+		byReason[r.Reason()]++
+		slog.Debug("Rejected line", "source", source, "line", r.Line, "text", r.Text, "error", r.Err)
+	}
+	slog.Info("Rejected lines", "source", source, "count", len(rejected), "reasons", byReason)
+}
@@ -0,0 +1,344 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+
+	"proxypool/internal/model"
+	"proxypool/internal/scraper/parser"
+)
+
+// SubscriptionReport summarises the last fetch of a SubscriptionSource.
+type SubscriptionReport struct {
+	Imported    int
+	Unsupported map[string]int // node type -> count
+}
+
+// SubscriptionSource imports plain HTTP and SOCKS nodes from Clash YAML
+// configs and V2Ray/Shadowsocks style subscriptions (optionally base64
+// encoded lists of share links). Other node types (ss, vmess, trojan, ...)
+// are skipped and counted in the report.
+type SubscriptionSource struct {
+	name string
+	url  string
+
+	mu   sync.Mutex
+	last SubscriptionReport
+}
+
+func NewSubscriptionSource(name, url string) *SubscriptionSource {
+	return &SubscriptionSource{
+		name: name,
+		url:  url,
+	}
+}
+
+func (s *SubscriptionSource) Name() string {
+	return s.name
+}
+
+// LastReport returns the report of the most recent successful Fetch.
+func (s *SubscriptionSource) LastReport() SubscriptionReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+func (s *SubscriptionSource) Fetch(ctx context.Context) ([]*model.Proxy, error) {
+	body, err := getBody(ctx, s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies, report := ParseSubscription(string(body))
+	if len(report.Unsupported) > 0 {
+		slog.Warn("Skipped unsupported subscription nodes", "source", s.name, "imported", report.Imported, "unsupported", report.Unsupported)
+	}
+
+	s.mu.Lock()
+	s.last = report
+	s.mu.Unlock()
+
+	return proxies, nil
+}
+
+// ParseSubscription detects the subscription format and extracts the nodes
+// we can use.
+func ParseSubscription(body string) ([]*model.Proxy, SubscriptionReport) {
+	var nodes []subNode
+	switch {
+	case isClashConfig(body):
+		nodes = parseClash(body)
+	default:
+		if decoded, ok := decodeBase64Subscription(body); ok {
+			body = decoded
+		}
+		nodes = parseShareLinks(body)
+	}
+
+	report := SubscriptionReport{Unsupported: make(map[string]int)}
+	var proxies []*model.Proxy
+	for _, n := range nodes {
+		p, reason := n.proxy()
+		if p == nil {
+			report.Unsupported[reason]++
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	report.Imported = len(proxies)
+	return proxies, report
+}
+
+// subNode is a single entry from a subscription, before conversion.
+type subNode struct {
+	kind     string // clash type or link scheme
+	server   string
+	port     string
+	username string
+	password string
+	tls      bool
+}
+
+// proxy converts the node into a model.Proxy. If the node can't be imported,
+// it returns nil and the report key describing why.
+func (n subNode) proxy() (*model.Proxy, string) {
+	kind := strings.ToLower(n.kind)
+	protocol, ok := parser.Protocol(kind)
+	if !ok {
+		if kind == "" {
+			return nil, "unknown"
+		}
+		return nil, kind
+	}
+	if protocol == model.ProtocolHTTP && n.tls {
+		protocol = model.ProtocolHTTPS
+	}
+
+	e, err := parser.ParseParts(n.server, n.port, parseOptions)
+	if err != nil {
+		return nil, "invalid"
+	}
+	e.Scheme = protocol
+	e.Username, e.Password = n.username, n.password
+	return e.Proxy(protocol), ""
+}
+
+// ---- Share links ----
+
+func decodeBase64Subscription(body string) (string, bool) {
+	compact := strings.Join(strings.Fields(body), "")
+	if compact == "" || strings.Contains(compact, "://") {
+		return "", false
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(compact); err == nil && strings.Contains(string(decoded), "://") {
+			return string(decoded), true
+		}
+	}
+	return "", false
+}
+
+func parseShareLinks(body string) []subNode {
+	var nodes []subNode
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		scheme, _, found := strings.Cut(line, "://")
+		if !found {
+			nodes = append(nodes, subNode{})
+			continue
+		}
+		switch strings.ToLower(scheme) {
+		case "http", "https", "socks", "socks5", "socks5h", "socks4", "socks4a":
+			nodes = append(nodes, parseProxyLink(line))
+		default:
+			// ss://, vmess://, trojan://, ... carry their own encodings we don't need to read.
+			nodes = append(nodes, subNode{kind: scheme})
+		}
+	}
+	return nodes
+}
+
+// parseProxyLink parses "scheme://[user:pass@]host:port[#remark]". The v2rayN
+// "socks://" form carries base64("user:pass") as the userinfo.
+func parseProxyLink(link string) subNode {
+	u, err := url.Parse(link)
+	if err != nil {
+		return subNode{kind: "invalid"}
+	}
+	n := subNode{
+		kind:   u.Scheme,
+		server: u.Hostname(),
+		port:   u.Port(),
+	}
+	if u.User != nil {
+		n.username = u.User.Username()
+		pass, hasPass := u.User.Password()
+		n.password = pass
+		if !hasPass {
+			if decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(n.username, "=")); err == nil {
+				if user, pass, ok := strings.Cut(string(decoded), ":"); ok {
+					n.username, n.password = user, pass
+				}
+			}
+		}
+	}
+	return n
+}
+
+// ---- Clash YAML ----
+//
+// Only the "proxies:" list is read, which keeps us clear of a YAML dependency.
+// Both flow ("- {name: a, type: socks5, ...}") and block style items are supported.
+
+func isClashConfig(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimRight(line, " \r\t"), "proxies:") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseClash(body string) []subNode {
+	var (
+		nodes      []subNode
+		inList     bool
+		item       map[string]string
+		itemIndent = -1 // indentation of the list's "-" markers
+		keyDepth   = -1 // indentation of the current block item's keys
+	)
+
+	flush := func() {
+		if item != nil {
+			nodes = append(nodes, clashNode(item))
+			item = nil
+		}
+	}
+
+	for _, raw := range strings.Split(body, "\n") {
+		line := strings.TrimRight(raw, " \r\t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if !inList {
+			if indent == 0 && strings.HasPrefix(line, "proxies:") {
+				inList = true
+			}
+			continue
+		}
+
+		// Any other top-level key ends the list.
+		if indent == 0 && !strings.HasPrefix(trimmed, "-") {
+			break
+		}
+
+		if itemIndent < 0 && strings.HasPrefix(trimmed, "-") {
+			itemIndent = indent
+		}
+
+		if rest, ok := strings.CutPrefix(trimmed, "-"); ok && indent == itemIndent {
+			flush()
+			rest = strings.TrimSpace(rest)
+			if strings.HasPrefix(rest, "{") {
+				nodes = append(nodes, clashNode(parseFlowMap(rest)))
+				continue
+			}
+			item = make(map[string]string)
+			keyDepth = indent + (len(trimmed) - len(strings.TrimLeft(trimmed[1:], " ")))
+			if k, v, ok := splitYAMLPair(rest); ok {
+				item[k] = v
+			}
+			continue
+		}
+
+		if item != nil && indent == keyDepth {
+			if k, v, ok := splitYAMLPair(trimmed); ok {
+				item[k] = v
+			}
+		}
+	}
+	flush()
+	return nodes
+}
+
+func clashNode(m map[string]string) subNode {
+	return subNode{
+		kind:     m["type"],
+		server:   m["server"],
+		port:     m["port"],
+		username: m["username"],
+		password: m["password"],
+		tls:      m["tls"] == "true",
+	}
+}
+
+// parseFlowMap parses a single-line YAML mapping such as "{a: 1, b: 'x'}".
+// Nested values are kept as raw strings.
+func parseFlowMap(s string) map[string]string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+
+	m := make(map[string]string)
+	var (
+		depth int
+		quote rune
+		start int
+	)
+	add := func(part string) {
+		if k, v, ok := splitYAMLPair(part); ok {
+			m[k] = v
+		}
+	}
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			depth--
+		case r == ',' && depth == 0:
+			add(s[start:i])
+			start = i + 1
+		}
+	}
+	add(s[start:])
+	return m
+}
+
+func splitYAMLPair(s string) (string, string, bool) {
+	k, v, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", "", false
+	}
+	return unquoteYAML(k), unquoteYAML(v), true
+}
+
+func unquoteYAML(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	// Strip trailing comments on plain scalars.
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
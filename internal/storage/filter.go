@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 10000
+)
+
+// Sort orders for List.
+const (
+	SortLatency    = "latency"    // lowest latency first (default)
+	SortThroughput = "throughput" // highest measured throughput first
+	SortScore      = "score"      // lowest estimated time to fetch 1 MiB first
+)
+
+// assumedKBps stands in for proxies whose throughput was never measured when
+// ranking by score.
+const assumedKBps = 100
+
+// scoreSQL estimates the milliseconds needed to fetch 1 MiB: one round trip
+// plus the transfer at the measured rate.
+var scoreSQL = fmt.Sprintf("latency_ms + 1024000 / COALESCE(NULLIF(throughput_kbps, 0), %d)", assumedKBps)
+
+// ProxyFilter narrows down the proxies returned by List.
+// Zero values mean "don't filter".
+type ProxyFilter struct {
+	Protocol    string
+	Country     string
+	ASN         int
+	NetworkType string // model.Network*
+	ExitIP      string
+
+	// Profile, if set, matches proxies whose latest run of that check profile passed.
+	Profile string
+
+	// CheckRegion, if set, matches proxies that passed their latest check
+	// from that region's agents within the last day, and ranks them by the
+	// latency measured there.
+	CheckRegion string
+
+	// MinKBps, if set, matches proxies measured at this throughput or faster.
+	MinKBps int
+
+	// SupportsHTTPS, if set, matches proxies that can (or can't) tunnel TLS.
+	SupportsHTTPS *bool
+
+	// SupportsIPv6, if set, matches proxies that can (or can't) reach IPv6 targets.
+	SupportsIPv6 *bool
+
+	// IPVersion, if set (4 or 6), matches proxies whose entry address is of that family.
+	IPVersion int
+
+	// DistinctExit returns at most one proxy per exit IP.
+	DistinctExit bool
+
+	// IncludeAuth also returns proxies that need credentials.
+	IncludeAuth bool
+
+	// IncludeTampered also returns proxies caught tampering with traffic.
+	IncludeTampered bool
+
+	// Sort is one of the Sort* orders; empty means SortLatency.
+	Sort string
+
+	Limit int
+}
+
+// ValidSort reports whether s is a known sort order.
+func ValidSort(s string) bool {
+	switch s {
+	case "", SortLatency, SortThroughput, SortScore:
+		return true
+	}
+	return false
+}
+
+// regionLatencySQL is the latency from f.CheckRegion, which where() always
+// passes as $1.
+const regionLatencySQL = "(SELECT rl.latency_ms FROM proxy_region_latency rl WHERE rl.proxy_id = proxies.id AND rl.region = $1)"
+
+// orderBy returns the ORDER BY expression for f.Sort. Ties break on ID so
+// results are stable. With a CheckRegion, latency is the one measured there.
+func (f ProxyFilter) orderBy() string {
+	latency, score := "latency_ms", scoreSQL
+	if f.CheckRegion != "" {
+		latency = regionLatencySQL
+		score = strings.Replace(scoreSQL, "latency_ms", regionLatencySQL, 1)
+	}
+	switch f.Sort {
+	case SortThroughput:
+		return "throughput_kbps DESC NULLS LAST, " + latency + " ASC, id"
+	case SortScore:
+		return score + " ASC, id"
+	}
+	return latency + " ASC, id"
+}
+
+func (f ProxyFilter) limit() int {
+	switch {
+	case f.Limit <= 0:
+		return defaultListLimit
+	case f.Limit > maxListLimit:
+		return maxListLimit
+	}
+	return f.Limit
+}
+
+// where builds the WHERE clause and its positional arguments.
+func (f ProxyFilter) where() (string, []any) {
+	// Alive, and confirmed by enough consecutive checks to be served.
+	conds := []string{"latency_ms > 0", "promoted_at IS NOT NULL"}
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	// First, so that orderBy can refer to it as $1.
+	if f.CheckRegion != "" {
+		add("EXISTS (SELECT 1 FROM proxy_region_latency rl WHERE rl.proxy_id = proxies.id AND rl.region = $%d"+
+			" AND rl.alive AND rl.checked_at >= NOW() - INTERVAL '1 day')", f.CheckRegion)
+	}
+	if f.Protocol != "" {
+		add("protocol = $%d", f.Protocol)
+	}
+	if f.Country != "" {
+		add("country = $%d", strings.ToUpper(f.Country))
+	}
+	if f.ASN != 0 {
+		add("asn = $%d", f.ASN)
+	}
+	if f.NetworkType != "" {
+		add("network_type = $%d", f.NetworkType)
+	}
+	if f.ExitIP != "" {
+		add("exit_ip = $%d::INET", f.ExitIP)
+	}
+	if f.Profile != "" {
+		add("EXISTS (SELECT 1 FROM proxy_profile_results r WHERE r.proxy_id = proxies.id AND r.profile = $%d AND r.passed)", f.Profile)
+	}
+	if f.MinKBps > 0 {
+		add("throughput_kbps >= $%d", f.MinKBps)
+	}
+	if f.SupportsHTTPS != nil {
+		add("supports_https = $%d", *f.SupportsHTTPS)
+	}
+	if f.SupportsIPv6 != nil {
+		add("supports_ipv6 = $%d", *f.SupportsIPv6)
+	}
+	if f.IPVersion != 0 {
+		add("family(ip) = $%d", f.IPVersion)
+	}
+	if !f.IncludeAuth {
+		conds = append(conds, "username_enc IS NULL")
+	}
+	if !f.IncludeTampered {
+		conds = append(conds, "NOT tampered")
+	}
+
+	return strings.Join(conds, " AND "), args
+}
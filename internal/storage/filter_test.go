@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProxyFilter_Where(t *testing.T) {
+	yes := true
+	tests := []struct {
+		name     string
+		filter   ProxyFilter
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:    "default",
+			filter:  ProxyFilter{},
+			wantSQL: "latency_ms > 0 AND promoted_at IS NOT NULL AND username_enc IS NULL AND NOT tampered",
+		},
+		{
+			name:     "protocol and country",
+			filter:   ProxyFilter{Protocol: "socks5", Country: "de", IncludeAuth: true},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND protocol = $1 AND country = $2 AND NOT tampered",
+			wantArgs: []any{"socks5", "DE"},
+		},
+		{
+			name:     "network",
+			filter:   ProxyFilter{ASN: 16509, NetworkType: "hosting"},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND asn = $1 AND network_type = $2 AND username_enc IS NULL AND NOT tampered",
+			wantArgs: []any{16509, "hosting"},
+		},
+		{
+			name:     "exit ip",
+			filter:   ProxyFilter{ExitIP: "1.2.3.4", IncludeAuth: true, IncludeTampered: true},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND exit_ip = $1::INET",
+			wantArgs: []any{"1.2.3.4"},
+		},
+		{
+			name:     "profile",
+			filter:   ProxyFilter{Profile: "google", IncludeAuth: true, IncludeTampered: true},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND EXISTS (SELECT 1 FROM proxy_profile_results r WHERE r.proxy_id = proxies.id AND r.profile = $1 AND r.passed)",
+			wantArgs: []any{"google"},
+		},
+		{
+			name:     "throughput",
+			filter:   ProxyFilter{MinKBps: 500, IncludeAuth: true, IncludeTampered: true},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND throughput_kbps >= $1",
+			wantArgs: []any{500},
+		},
+		{
+			name:     "https",
+			filter:   ProxyFilter{SupportsHTTPS: &yes},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND supports_https = $1 AND username_enc IS NULL AND NOT tampered",
+			wantArgs: []any{true},
+		},
+		{
+			name:     "ipv6",
+			filter:   ProxyFilter{SupportsIPv6: &yes, IPVersion: 6, IncludeAuth: true, IncludeTampered: true},
+			wantSQL:  "latency_ms > 0 AND promoted_at IS NOT NULL AND supports_ipv6 = $1 AND family(ip) = $2",
+			wantArgs: []any{true, 6},
+		},
+		{
+			name:   "check region",
+			filter: ProxyFilter{Protocol: "http", CheckRegion: "eu-west", IncludeAuth: true, IncludeTampered: true},
+			wantSQL: "latency_ms > 0 AND promoted_at IS NOT NULL AND EXISTS (SELECT 1 FROM proxy_region_latency rl WHERE rl.proxy_id = proxies.id" +
+				" AND rl.region = $1 AND rl.alive AND rl.checked_at >= NOW() - INTERVAL '1 day') AND protocol = $2",
+			wantArgs: []any{"eu-west", "http"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.filter.where()
+			if sql != tt.wantSQL {
+				t.Errorf("where() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("where() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestProxyFilter_OrderBy(t *testing.T) {
+	tests := map[string]string{
+		"":             "latency_ms ASC, id",
+		SortThroughput: "throughput_kbps DESC NULLS LAST, latency_ms ASC, id",
+		SortScore:      "latency_ms + 1024000 / COALESCE(NULLIF(throughput_kbps, 0), 100) ASC, id",
+	}
+	for sort, want := range tests {
+		if got := (ProxyFilter{Sort: sort}).orderBy(); got != want {
+			t.Errorf("orderBy(%q) = %q, want %q", sort, got, want)
+		}
+	}
+
+	regional := ProxyFilter{CheckRegion: "eu-west", Sort: SortScore}.orderBy()
+	if want := regionLatencySQL + " + 1024000 / COALESCE(NULLIF(throughput_kbps, 0), 100) ASC, id"; regional != want {
+		t.Errorf("orderBy with region = %q, want %q", regional, want)
+	}
+}
+
+func TestProxyFilter_Limit(t *testing.T) {
+	if got := (ProxyFilter{}).limit(); got != defaultListLimit {
+		t.Errorf("limit() = %d, want %d", got, defaultListLimit)
+	}
+	if got := (ProxyFilter{Limit: 1 << 30}).limit(); got != maxListLimit {
+		t.Errorf("limit() = %d, want %d", got, maxListLimit)
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Check lanes sort proxies by how urgently they need a check, so that fresh
+// intake and the proxies being served don't wait behind the dead backlog.
+const (
+	LaneServing = "serving" // alive and promoted: what the API hands out
+	LaneNew     = "new"     // never checked, or alive but not yet promoted
+	LaneRecent  = "recent"  // dead, but alive within the last day
+	LaneDead    = "dead"    // dead for longer, or never alive
+)
+
+// laneConditions selects each lane's proxies. Together they cover every
+// checkable proxy exactly once.
+var laneConditions = map[string]string{
+	LaneServing: "latency_ms > 0 AND promoted_at IS NOT NULL",
+	LaneNew:     "(last_checked_at IS NULL OR (latency_ms > 0 AND promoted_at IS NULL))",
+	LaneRecent:  "last_checked_at IS NOT NULL AND COALESCE(latency_ms, 0) = 0 AND last_alive_at >= NOW() - INTERVAL '1 day'",
+	LaneDead:    "last_checked_at IS NOT NULL AND COALESCE(latency_ms, 0) = 0 AND (last_alive_at IS NULL OR last_alive_at < NOW() - INTERVAL '1 day')",
+}
+
+// Lane is a check lane with its share of the producer's batches and how
+// long its proxies rest between checks.
+type Lane struct {
+	Name     string
+	Weight   int
+	Interval time.Duration
+}
+
+// DefaultLanes is the lane spec used when none is configured.
+const DefaultLanes = "serving=4/5m,new=3,recent=2/15m,dead=1/1h"
+
+// ParseLanes parses a spec such as "serving=4/5m,new=3,recent=2/15m,dead=1/1h":
+// lane=weight, optionally followed by /interval. Lanes keep their priority
+// order (serving, new, recent, dead) whatever the order in the spec; lanes
+// left out are not checked.
+func ParseLanes(spec string) ([]Lane, error) {
+	byName := make(map[string]Lane)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("lane %q: missing weight", item)
+		}
+		name = strings.TrimSpace(name)
+		if _, known := laneConditions[name]; !known {
+			return nil, fmt.Errorf("unknown lane %q", name)
+		}
+		if _, dup := byName[name]; dup {
+			return nil, fmt.Errorf("lane %q given twice", name)
+		}
+
+		lane := Lane{Name: name}
+		weight, interval, hasInterval := strings.Cut(rest, "/")
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("lane %q: invalid weight %q", name, weight)
+		}
+		lane.Weight = n
+		if hasInterval {
+			d, err := time.ParseDuration(strings.TrimSpace(interval))
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("lane %q: invalid interval %q", name, interval)
+			}
+			lane.Interval = d
+		}
+		byName[name] = lane
+	}
+
+	var lanes []Lane
+	for _, name := range []string{LaneServing, LaneNew, LaneRecent, LaneDead} {
+		if lane, ok := byName[name]; ok {
+			lanes = append(lanes, lane)
+		}
+	}
+	if len(lanes) == 0 {
+		return nil, fmt.Errorf("no lanes")
+	}
+	return lanes, nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLanes(t *testing.T) {
+	lanes, err := ParseLanes("dead=1/1h, new=3,serving=4/5m")
+	if err != nil {
+		t.Fatalf("ParseLanes failed: %v", err)
+	}
+	want := []Lane{
+		{Name: LaneServing, Weight: 4, Interval: 5 * time.Minute},
+		{Name: LaneNew, Weight: 3},
+		{Name: LaneDead, Weight: 1, Interval: time.Hour},
+	}
+	if !reflect.DeepEqual(lanes, want) {
+		t.Errorf("ParseLanes = %+v, want %+v", lanes, want)
+	}
+
+	if _, err := ParseLanes(DefaultLanes); err != nil {
+		t.Errorf("DefaultLanes doesn't parse: %v", err)
+	}
+
+	for _, spec := range []string{"", "serving", "fresh=1", "new=0", "new=x", "new=1/soon", "new=1,new=2"} {
+		if _, err := ParseLanes(spec); err == nil {
+			t.Errorf("ParseLanes(%q) should fail", spec)
+		}
+	}
+}
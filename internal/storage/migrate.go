@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLock is the advisory lock key that serializes migrations between
+// instances starting at the same time.
+const migrationLock = 0x70726f78796d6967
+
+// Migrate applies the embedded SQL migrations that haven't run yet.
+// Migrations are applied in file name order, each in its own transaction.
+func (r *PostgresRepository) Migrate(ctx context.Context) error {
+	_, err := r.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	names, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("list migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+		if err := r.applyMigration(ctx, name, version); err != nil {
+			return fmt.Errorf("migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func (r *PostgresRepository) applyMigration(ctx context.Context, name, version string) error {
+	var applied bool
+	err := r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied)
+	if err != nil {
+		return fmt.Errorf("check version: %w", err)
+	}
+	if applied {
+		return nil
+	}
+
+	sql, err := migrationFS.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Another instance may have applied it while we waited for the lock.
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(migrationLock)); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied)
+	if err != nil {
+		return fmt.Errorf("check version: %w", err)
+	}
+	if applied {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, string(sql)); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+		return fmt.Errorf("record version: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	slog.Info("Applied migration", "version", version)
+	return nil
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS proxies (
+    id              BIGSERIAL PRIMARY KEY,
+    ip              INET NOT NULL,
+    port            INTEGER NOT NULL,
+    protocol        TEXT,
+    country         TEXT,
+    anonymity       TEXT,
+    latency_ms      INTEGER,
+    last_checked_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (ip, port)
+);
+
+CREATE INDEX IF NOT EXISTS proxies_last_checked_at_idx ON proxies (last_checked_at ASC NULLS FIRST);
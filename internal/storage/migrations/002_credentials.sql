@@ -0,0 +1,3 @@
+-- Proxy credentials, AES-GCM encrypted by the application.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS username_enc BYTEA;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS password_enc BYTEA;
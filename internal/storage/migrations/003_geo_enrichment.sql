@@ -0,0 +1,11 @@
+-- Location and network enrichment (GeoLite2-City + GeoLite2-ASN).
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS city TEXT;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS region TEXT;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS asn INTEGER;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS asn_org TEXT;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS network_type TEXT;
+
+CREATE INDEX IF NOT EXISTS proxies_asn_idx ON proxies (asn);
+CREATE INDEX IF NOT EXISTS proxies_network_type_idx ON proxies (network_type);
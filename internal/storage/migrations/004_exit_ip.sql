@@ -0,0 +1,4 @@
+-- Exit IP observed by the judge; differs from ip for multi-hop proxies.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS exit_ip INET;
+
+CREATE INDEX IF NOT EXISTS proxies_exit_ip_idx ON proxies (exit_ip);
@@ -0,0 +1,2 @@
+-- Whether the proxy can tunnel TLS (CONNECT + handshake). NULL if never tested.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS supports_https BOOLEAN;
@@ -0,0 +1,3 @@
+-- Proxies caught modifying content or forging certificates.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS tampered BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS tamper_reason TEXT;
@@ -0,0 +1,12 @@
+-- Latest result of each named check profile per proxy.
+CREATE TABLE IF NOT EXISTS proxy_profile_results (
+    proxy_id   BIGINT NOT NULL REFERENCES proxies (id) ON DELETE CASCADE,
+    profile    TEXT NOT NULL,
+    passed     BOOLEAN NOT NULL,
+    latency_ms INTEGER,
+    error      TEXT,
+    checked_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (proxy_id, profile)
+);
+
+CREATE INDEX IF NOT EXISTS proxy_profile_results_profile_idx ON proxy_profile_results (profile, passed);
@@ -0,0 +1,2 @@
+-- Download rate measured by the throughput validator. NULL if never measured.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS throughput_kbps INTEGER;
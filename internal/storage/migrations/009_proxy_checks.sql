@@ -0,0 +1,16 @@
+-- Check history with the per-phase timing breakdown. Pruned by the engine.
+CREATE TABLE IF NOT EXISTS proxy_checks (
+    id               BIGSERIAL PRIMARY KEY,
+    proxy_id         BIGINT NOT NULL REFERENCES proxies (id) ON DELETE CASCADE,
+    checked_at       TIMESTAMPTZ NOT NULL,
+    alive            BOOLEAN NOT NULL,
+    failed_validator TEXT,
+    connect_ms       INTEGER NOT NULL DEFAULT 0,
+    handshake_ms     INTEGER NOT NULL DEFAULT 0,
+    tls_ms           INTEGER NOT NULL DEFAULT 0,
+    ttfb_ms          INTEGER NOT NULL DEFAULT 0,
+    total_ms         INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS proxy_checks_proxy_id_idx ON proxy_checks (proxy_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS proxy_checks_checked_at_idx ON proxy_checks (checked_at);
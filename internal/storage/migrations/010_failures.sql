@@ -0,0 +1,5 @@
+-- Why the latest check failed, and why each recorded check failed.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS last_failure TEXT;
+ALTER TABLE proxy_checks ADD COLUMN IF NOT EXISTS failure TEXT;
+
+CREATE INDEX IF NOT EXISTS proxies_last_failure_idx ON proxies (last_failure) WHERE last_failure IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Consecutive passed checks, and when the proxy first earned enough of them
+-- to be served. Proxies that are alive now are grandfathered in.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS success_streak INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS promoted_at TIMESTAMPTZ;
+ALTER TABLE proxy_checks ADD COLUMN IF NOT EXISTS attempts SMALLINT NOT NULL DEFAULT 1;
+
+UPDATE proxies SET success_streak = 1, promoted_at = COALESCE(last_checked_at, NOW())
+WHERE latency_ms > 0 AND promoted_at IS NULL;
@@ -0,0 +1,2 @@
+-- Whether the proxy can reach IPv6 targets. NULL if never tested.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS supports_ipv6 BOOLEAN;
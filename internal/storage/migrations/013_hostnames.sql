@@ -0,0 +1,21 @@
+-- Proxies listed by hostname. ip holds the current resolution and is NULL
+-- until the first one; such proxies are unique by (host, port) instead.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS host TEXT;
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ;
+ALTER TABLE proxies ALTER COLUMN ip DROP NOT NULL;
+ALTER TABLE proxies ADD CONSTRAINT proxies_ip_or_host CHECK (ip IS NOT NULL OR host IS NOT NULL);
+
+ALTER TABLE proxies DROP CONSTRAINT IF EXISTS proxies_ip_port_key;
+CREATE UNIQUE INDEX IF NOT EXISTS proxies_ip_port_key ON proxies (ip, port) WHERE host IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS proxies_host_port_key ON proxies (host, port) WHERE host IS NOT NULL;
+CREATE INDEX IF NOT EXISTS proxies_resolved_at_idx ON proxies (resolved_at ASC NULLS FIRST) WHERE host IS NOT NULL;
+
+-- Every address a hostname proxy has resolved to, from when.
+CREATE TABLE IF NOT EXISTS proxy_resolutions (
+    id          BIGSERIAL PRIMARY KEY,
+    proxy_id    BIGINT NOT NULL REFERENCES proxies (id) ON DELETE CASCADE,
+    ip          INET NOT NULL,
+    resolved_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS proxy_resolutions_proxy_id_idx ON proxy_resolutions (proxy_id, resolved_at DESC);
@@ -0,0 +1,7 @@
+-- When the proxy last passed a check; decides between the recent and dead
+-- check lanes.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS last_alive_at TIMESTAMPTZ;
+
+UPDATE proxies SET last_alive_at = last_checked_at WHERE latency_ms > 0;
+
+CREATE INDEX IF NOT EXISTS proxies_serving_idx ON proxies (last_checked_at) WHERE latency_ms > 0 AND promoted_at IS NOT NULL;
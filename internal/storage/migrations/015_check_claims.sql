@@ -0,0 +1,3 @@
+-- Proxies handed out for a check are claimed until the check is written, so
+-- that instances sharing the database don't check the same proxy.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS check_claimed_until TIMESTAMPTZ;
@@ -0,0 +1,15 @@
+-- Latest check of each proxy from each region that runs checker agents.
+-- Rows are created when an agent claims the proxy, so checked_at is NULL
+-- until the first result comes in.
+CREATE TABLE IF NOT EXISTS proxy_region_latency (
+    proxy_id      BIGINT NOT NULL REFERENCES proxies (id) ON DELETE CASCADE,
+    region        TEXT NOT NULL,
+    alive         BOOLEAN NOT NULL DEFAULT FALSE,
+    latency_ms    INTEGER,
+    failure       TEXT,
+    checked_at    TIMESTAMPTZ,
+    claimed_until TIMESTAMPTZ,
+    PRIMARY KEY (proxy_id, region)
+);
+
+CREATE INDEX IF NOT EXISTS proxy_region_latency_region_idx ON proxy_region_latency (region, alive, latency_ms);
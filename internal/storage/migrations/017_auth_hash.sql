@@ -0,0 +1,3 @@
+-- Keyed fingerprint of the stored credentials. The ciphertexts use random
+-- nonces, so this is how a scrape tells whether credentials changed.
+ALTER TABLE proxies ADD COLUMN IF NOT EXISTS auth_hash BYTEA;
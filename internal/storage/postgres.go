@@ -2,7 +2,11 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
 
 	"proxypool/internal/model"
 
@@ -11,15 +15,18 @@ import (
 )
 
 type PostgresRepository struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	cipher *Cipher // Encrypts proxy credentials. Nil disables storing them.
 }
 
-func NewPostgresRepository(dbURL string) (*PostgresRepository, error) {
+// NewPostgresRepository connects to dbURL. cipher may be nil, in which case
+// proxies with credentials are not stored.
+func NewPostgresRepository(dbURL string, cipher *Cipher) (*PostgresRepository, error) {
 	config, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database config: %w", err)
 	}
-	
+
 	// Fix for Supabase Transaction Pooler (PgBouncer) "prepared statement already exists" error
 	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
 
@@ -28,39 +35,56 @@ func NewPostgresRepository(dbURL string) (*PostgresRepository, error) {
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
-	return &PostgresRepository{pool: pool}, nil
+	return &PostgresRepository{pool: pool, cipher: cipher}, nil
 }
 
 func (r *PostgresRepository) Close() {
 	r.pool.Close()
 }
 
-// SaveBatch inserts new proxies. Duplicates (ip, port) are ignored.
+// SaveBatch inserts new proxies. Duplicates (ip, port), or (host, port) for
+// hostname proxies, are ignored.
 func (r *PostgresRepository) SaveBatch(ctx context.Context, proxies []*model.Proxy) error {
 	if len(proxies) == 0 {
 		return nil
 	}
 
-	// We use COPY for high performance, but COPY doesn't support ON CONFLICT natively in a simple way 
-	// without using a temp table. 
+	// We use COPY for high performance, but COPY doesn't support ON CONFLICT natively in a simple way
+	// without using a temp table.
 	// For "World Scale", thousands of proxies, INSERT ... ON CONFLICT is safer and "fast enough" if batched correctly.
-	// But building a huge generic INSERT string is messy. 
+	// But building a huge generic INSERT string is messy.
 	// Let's use pgx.Batch.
 
 	batch := &pgx.Batch{}
+	queued := 0
 	for _, p := range proxies {
-		batch.Queue(`
-			INSERT INTO proxies (ip, port, protocol, created_at)
-			VALUES ($1, $2, $3, NOW())
-			ON CONFLICT (ip, port) DO NOTHING
-		`, p.IP, p.Port, p.Protocol)
+		userEnc, passEnc, authHash, err := r.encryptAuth(p)
+		if err != nil {
+			slog.Warn("Dropping proxy with credentials", "proxy", p.Address(), "error", err)
+			continue
+		}
+		// Credentials of an already known proxy are refreshed; everything else is left alone.
+		if p.IsHostname() {
+			batch.Queue(`
+				INSERT INTO proxies (host, port, protocol, username_enc, password_enc, auth_hash, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, NOW())
+				ON CONFLICT (host, port) WHERE host IS NOT NULL DO UPDATE
+			`+updateAuthSQL, p.Host, p.Port, p.Protocol, userEnc, passEnc, authHash)
+		} else {
+			batch.Queue(`
+				INSERT INTO proxies (ip, port, protocol, username_enc, password_enc, auth_hash, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, NOW())
+				ON CONFLICT (ip, port) WHERE host IS NULL DO UPDATE
+			`+updateAuthSQL, p.IP, p.Port, p.Protocol, userEnc, passEnc, authHash)
+		}
+		queued++
 	}
 
 	br := r.pool.SendBatch(ctx, batch)
 	defer br.Close()
 
 	// We must execute the batch results to ensure it actually happened and check errors
-	for i := 0; i < len(proxies); i++ {
+	for i := 0; i < queued; i++ {
 		_, err := br.Exec()
 		if err != nil {
 			return fmt.Errorf("failed to insert batch item %d: %w", i, err)
@@ -70,18 +94,155 @@ func (r *PostgresRepository) SaveBatch(ctx context.Context, proxies []*model.Pro
 	return nil
 }
 
-// GetProxiesToCheck returns proxies that haven't been checked recently.
-// Uses FOR UPDATE SKIP LOCKED to allow multiple concurrent consumers.
-func (r *PostgresRepository) GetProxiesToCheck(ctx context.Context, limit int) ([]*model.Proxy, error) {
+// authChangedSQL holds in the SaveBatch upsert when the scrape lists other
+// credentials than the stored ones. Rows stored before auth_hash existed
+// can't tell and count as unchanged.
+const authChangedSQL = `proxies.auth_hash IS DISTINCT FROM EXCLUDED.auth_hash
+	AND (proxies.auth_hash IS NOT NULL OR proxies.username_enc IS NULL)`
+
+// updateAuthSQL refreshes the credentials of a known proxy. New credentials
+// haven't been checked, so the proxy is queued for a check and has to earn
+// its success streak again before it is served.
+const updateAuthSQL = `
+	SET username_enc = EXCLUDED.username_enc, password_enc = EXCLUDED.password_enc, auth_hash = EXCLUDED.auth_hash,
+		last_checked_at = CASE WHEN ` + authChangedSQL + ` THEN NULL ELSE proxies.last_checked_at END,
+		promoted_at = CASE WHEN ` + authChangedSQL + ` THEN NULL ELSE proxies.promoted_at END,
+		success_streak = CASE WHEN ` + authChangedSQL + ` THEN 0 ELSE proxies.success_streak END
+	WHERE EXCLUDED.username_enc IS NOT NULL
+`
+
+// encryptAuth encrypts the proxy's credentials for storage and returns
+// their fingerprint.
+func (r *PostgresRepository) encryptAuth(p *model.Proxy) (userEnc, passEnc, authHash []byte, err error) {
+	if !p.HasAuth() {
+		return nil, nil, nil, nil
+	}
+	if r.cipher == nil {
+		return nil, nil, nil, errors.New("credentials key not configured")
+	}
+	if userEnc, err = r.cipher.Encrypt(p.Username); err != nil {
+		return nil, nil, nil, err
+	}
+	if passEnc, err = r.cipher.Encrypt(p.Password); err != nil {
+		return nil, nil, nil, err
+	}
+	return userEnc, passEnc, r.cipher.Fingerprint(p.Username, p.Password), nil
+}
+
+// decryptAuth fills in the proxy's credentials.
+func (r *PostgresRepository) decryptAuth(p *model.Proxy, userEnc, passEnc []byte) error {
+	if r.cipher == nil {
+		return errors.New("credentials key not configured")
+	}
+	user, err := r.cipher.Decrypt(userEnc)
+	if err != nil {
+		return fmt.Errorf("username: %w", err)
+	}
+	pass, err := r.cipher.Decrypt(passEnc)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	p.Username, p.Password = user, pass
+	return nil
+}
+
+// proxyColumns is the select list understood by scanProxy. Addresses are
+// read with host() as ::TEXT would append the prefix length ("/32", "/128").
+const proxyColumns = `id, COALESCE(host(ip), ''), port, COALESCE(protocol, ''), COALESCE(country, ''), COALESCE(anonymity, ''), COALESCE(latency_ms, 0), last_checked_at, created_at, username_enc, password_enc,
+	COALESCE(city, ''), COALESCE(region, ''), COALESCE(latitude, 0), COALESCE(longitude, 0), COALESCE(asn, 0), COALESCE(asn_org, ''), COALESCE(network_type, ''),
+	COALESCE(host(exit_ip), ''), supports_https, tampered, COALESCE(tamper_reason, ''),
+	COALESCE(throughput_kbps, 0), COALESCE(last_failure, ''), success_streak, promoted_at, supports_ipv6,
+	COALESCE(host, ''), resolved_at, last_alive_at, auth_hash`
+
+// scanProxy scans a row selected with proxyColumns, decrypting credentials.
+// Columns selected after proxyColumns are scanned into extra.
+func (r *PostgresRepository) scanProxy(row pgx.Row, extra ...any) (*model.Proxy, error) {
+	p := &model.Proxy{}
+	var userEnc, passEnc []byte
+	dest := []any{
+		&p.ID,
+		&p.IP,
+		&p.Port,
+		&p.Protocol,
+		&p.Country,
+		&p.Anonymity,
+		&p.LatencyMS,
+		&p.LastCheckedAt,
+		&p.CreatedAt,
+		&userEnc,
+		&passEnc,
+		&p.City,
+		&p.Region,
+		&p.Latitude,
+		&p.Longitude,
+		&p.ASN,
+		&p.ASNOrg,
+		&p.NetworkType,
+		&p.ExitIP,
+		&p.SupportsHTTPS,
+		&p.Tampered,
+		&p.TamperReason,
+		&p.ThroughputKBps,
+		&p.LastFailure,
+		&p.SuccessStreak,
+		&p.PromotedAt,
+		&p.SupportsIPv6,
+		&p.Host,
+		&p.ResolvedAt,
+		&p.LastAliveAt,
+		&p.AuthHash,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("scan failed: %w", err)
+	}
+
+	if len(userEnc) > 0 {
+		// A proxy we can't decrypt is still returned; it will simply fail auth.
+		if err := r.decryptAuth(p, userEnc, passEnc); err != nil {
+			slog.Warn("Cannot decrypt proxy credentials", "id", p.ID, "error", err)
+		}
+	}
+	return p, nil
+}
+
+// checkClaim is how long a proxy returned by GetProxiesToCheck stays
+// claimed. Writing the check releases it; the expiry only matters for
+// proxies whose instance went away or never got round to them.
+const checkClaim = 5 * time.Minute
+
+// GetProxiesToCheck claims and returns the lane's proxies that haven't been
+// checked since checkedBefore. Claimed proxies aren't returned again, by
+// this or any other instance, until their check is written or the claim
+// expires. Hostname proxies are skipped until they have been resolved.
+// Ties break on ID so that repeated calls page consistently.
+func (r *PostgresRepository) GetProxiesToCheck(ctx context.Context, lane string, checkedBefore time.Time, limit int) ([]*model.Proxy, error) {
+	cond, ok := laneConditions[lane]
+	if !ok {
+		return nil, fmt.Errorf("unknown lane %q", lane)
+	}
 	query := `
-		SELECT id, ip::TEXT, port, COALESCE(protocol, ''), COALESCE(country, ''), COALESCE(anonymity, ''), COALESCE(latency_ms, 0), last_checked_at, created_at
-		FROM proxies
-		ORDER BY last_checked_at ASC NULLS FIRST
-		LIMIT $1
-		FOR UPDATE SKIP LOCKED
+		WITH due AS (
+			SELECT id
+			FROM proxies
+			WHERE ip IS NOT NULL AND ` + cond + `
+				AND (last_checked_at IS NULL OR last_checked_at < $1)
+				AND (check_claimed_until IS NULL OR check_claimed_until < NOW())
+			ORDER BY last_checked_at ASC NULLS FIRST, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE proxies
+			SET check_claimed_until = NOW() + make_interval(secs => $3)
+			FROM due
+			WHERE proxies.id = due.id
+			RETURNING proxies.*
+		)
+		SELECT ` + proxyColumns + `
+		FROM claimed
+		ORDER BY last_checked_at ASC NULLS FIRST, id
 	`
 
-	rows, err := r.pool.Query(ctx, query, limit)
+	rows, err := r.pool.Query(ctx, query, checkedBefore, limit, checkClaim.Seconds())
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -89,65 +250,470 @@ func (r *PostgresRepository) GetProxiesToCheck(ctx context.Context, limit int) (
 
 	var result []*model.Proxy
 	for rows.Next() {
-		p := &model.Proxy{}
-		var ipStr string
-		err := rows.Scan(
-			&p.ID,
-			&ipStr,
-			&p.Port,
-			&p.Protocol,
-			&p.Country,
-			&p.Anonymity,
-			&p.LatencyMS,
-			&p.LastCheckedAt,
-			&p.CreatedAt,
+		p, err := r.scanProxy(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// List returns alive proxies matching the filter, best first (see ProxyFilter.Sort).
+func (r *PostgresRepository) List(ctx context.Context, f ProxyFilter) ([]*model.Proxy, error) {
+	where, args := f.where()
+	if f.DistinctExit {
+		// Keep only the fastest proxy per exit IP.
+		where += `
+			AND id IN (
+				SELECT DISTINCT ON (COALESCE(exit_ip, ip)) id
+				FROM proxies
+				WHERE ` + where + `
+				ORDER BY COALESCE(exit_ip, ip), ` + f.orderBy() + `
+			)`
+	}
+	args = append(args, f.limit())
+	columns := proxyColumns
+	if f.CheckRegion != "" {
+		columns += ", " + regionLatencySQL
+	}
+	query := `
+		SELECT ` + columns + `
+		FROM proxies
+		WHERE ` + where + `
+		ORDER BY ` + f.orderBy() + `
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.Proxy
+	for rows.Next() {
+		var regionLatency *int
+		var extra []any
+		if f.CheckRegion != "" {
+			extra = append(extra, &regionLatency)
+		}
+		p, err := r.scanProxy(rows, extra...)
+		if err != nil {
+			return nil, err
+		}
+		if regionLatency != nil {
+			p.RegionLatencyMS = *regionLatency
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// ListPage returns up to limit proxies with ID greater than afterID, in ID order.
+func (r *PostgresRepository) ListPage(ctx context.Context, afterID int64, limit int) ([]*model.Proxy, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+proxyColumns+`
+		FROM proxies
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list page query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.Proxy
+	for rows.Next() {
+		p, err := r.scanProxy(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// UpdateGeoBatch writes only the GeoIP derived fields of the proxies.
+func (r *PostgresRepository) UpdateGeoBatch(ctx context.Context, proxies []*model.Proxy) error {
+	batch := &pgx.Batch{}
+	for _, p := range proxies {
+		batch.Queue(`
+			UPDATE proxies
+			SET country = $1, city = $2, region = $3, latitude = $4, longitude = $5,
+				asn = NULLIF($6, 0), asn_org = $7, network_type = $8
+			WHERE id = $9
+		`, p.Country, p.City, p.Region, p.Latitude, p.Longitude, p.ASN, p.ASNOrg, p.NetworkType, p.ID)
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < len(proxies); i++ {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to update geo batch item %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// GetProxiesForProfile returns up to limit alive proxies whose result for
+// profile is missing or older than staleBefore.
+func (r *PostgresRepository) GetProxiesForProfile(ctx context.Context, profile string, staleBefore time.Time, limit int) ([]*model.Proxy, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+proxyColumns+`
+		FROM proxies
+		WHERE latency_ms > 0 AND NOT EXISTS (
+			SELECT 1 FROM proxy_profile_results r
+			WHERE r.proxy_id = proxies.id AND r.profile = $1 AND r.checked_at >= $2
+		)
+		ORDER BY id
+		LIMIT $3
+	`, profile, staleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("profile query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.Proxy
+	for rows.Next() {
+		p, err := r.scanProxy(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// SaveProfileResults upserts the latest result per proxy and profile.
+func (r *PostgresRepository) SaveProfileResults(ctx context.Context, results []*model.ProfileResult) error {
+	batch := &pgx.Batch{}
+	for _, res := range results {
+		batch.Queue(`
+			INSERT INTO proxy_profile_results (proxy_id, profile, passed, latency_ms, error, checked_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+			ON CONFLICT (proxy_id, profile) DO UPDATE
+			SET passed = EXCLUDED.passed, latency_ms = EXCLUDED.latency_ms,
+				error = EXCLUDED.error, checked_at = EXCLUDED.checked_at
+		`, res.ProxyID, res.Profile, res.Passed, res.LatencyMS, res.Error, res.CheckedAt)
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < len(results); i++ {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to save profile result %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// regionClaim is how long a proxy returned by GetProxiesForRegion stays
+// claimed for the region. Agents report long before; the expiry only
+// matters for agents that went away.
+const regionClaim = 5 * time.Minute
+
+// GetProxiesForRegion claims and returns up to limit alive proxies that
+// have no result from region since staleBefore, the longest waiting first.
+// Claimed proxies aren't handed to the region's other agents until the
+// result is saved or the claim expires.
+func (r *PostgresRepository) GetProxiesForRegion(ctx context.Context, region string, staleBefore time.Time, limit int) ([]*model.Proxy, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT p.id, rl.checked_at
+			FROM proxies p
+			LEFT JOIN proxy_region_latency rl ON rl.proxy_id = p.id AND rl.region = $1
+			WHERE p.latency_ms > 0 AND p.ip IS NOT NULL
+				AND (rl.checked_at IS NULL OR rl.checked_at < $2)
+				AND (rl.claimed_until IS NULL OR rl.claimed_until < NOW())
+			ORDER BY rl.checked_at ASC NULLS FIRST, p.id
+			LIMIT $3
+			FOR UPDATE OF p SKIP LOCKED
+		), claimed AS (
+			INSERT INTO proxy_region_latency (proxy_id, region, claimed_until)
+			SELECT id, $1, NOW() + make_interval(secs => $4) FROM due
+			ON CONFLICT (proxy_id, region) DO UPDATE SET claimed_until = EXCLUDED.claimed_until
+		)
+		SELECT `+proxyColumns+`
+		FROM proxies
+		JOIN due USING (id)
+		ORDER BY due.checked_at ASC NULLS FIRST, id
+	`, region, staleBefore, limit, regionClaim.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("region query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.Proxy
+	for rows.Next() {
+		p, err := r.scanProxy(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// SaveRegionResults stores the latest result per proxy and region,
+// releasing the claims. Results for proxies that no longer exist are
+// dropped.
+func (r *PostgresRepository) SaveRegionResults(ctx context.Context, results []*model.RegionResult) error {
+	batch := &pgx.Batch{}
+	for _, res := range results {
+		batch.Queue(`
+			INSERT INTO proxy_region_latency (proxy_id, region, alive, latency_ms, failure, checked_at)
+			SELECT id, $2, $3, NULLIF($4, 0), NULLIF($5, ''), $6::TIMESTAMPTZ
+			FROM proxies WHERE id = $1
+			ON CONFLICT (proxy_id, region) DO UPDATE
+			SET alive = EXCLUDED.alive, latency_ms = EXCLUDED.latency_ms,
+				failure = EXCLUDED.failure, checked_at = EXCLUDED.checked_at, claimed_until = NULL
+		`, res.ProxyID, res.Region, res.Alive, res.LatencyMS, res.Failure, res.CheckedAt)
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < len(results); i++ {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to save region result %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// SaveChecks appends entries to the check history.
+func (r *PostgresRepository) SaveChecks(ctx context.Context, checks []*model.CheckRecord) error {
+	batch := &pgx.Batch{}
+	for _, c := range checks {
+		batch.Queue(`
+			INSERT INTO proxy_checks (proxy_id, checked_at, alive, failed_validator, failure, attempts,
+				connect_ms, handshake_ms, tls_ms, ttfb_ms, total_ms)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10, $11)
+		`, c.ProxyID, c.CheckedAt, c.Alive, c.FailedValidator, c.Failure, max(1, c.Attempts),
+			c.ConnectMS, c.HandshakeMS, c.TLSMS, c.TTFBMS, c.TotalMS)
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < len(checks); i++ {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to save check %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// FailureStats counts failures by class.
+func (r *PostgresRepository) FailureStats(ctx context.Context, since time.Time) (*FailureStats, error) {
+	stats := &FailureStats{
+		Since:  since,
+		Window: make(map[string]int64),
+		Dead:   make(map[string]int64),
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(failure, 'other'), COUNT(*)
+		FROM proxy_checks
+		WHERE NOT alive AND checked_at >= $1
+		GROUP BY 1
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failure stats query failed: %w", err)
+	}
+	if err := collectCounts(rows, stats.Window); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT last_failure, COUNT(*)
+		FROM proxies
+		WHERE last_failure IS NOT NULL
+		GROUP BY 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failure stats query failed: %w", err)
+	}
+	if err := collectCounts(rows, stats.Dead); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func collectCounts(rows pgx.Rows, into map[string]int64) error {
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			key string
+			n   int64
 		)
+		if err := rows.Scan(&key, &n); err != nil {
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		into[key] = n
+	}
+	return rows.Err()
+}
+
+// GetProxiesToResolve returns hostname proxies due for a lookup, the
+// longest unresolved first.
+func (r *PostgresRepository) GetProxiesToResolve(ctx context.Context, staleBefore time.Time, limit int) ([]*model.Proxy, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+proxyColumns+`
+		FROM proxies
+		WHERE host IS NOT NULL AND (resolved_at IS NULL OR resolved_at < $1)
+		ORDER BY resolved_at ASC NULLS FIRST
+		LIMIT $2
+	`, staleBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("resolve query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*model.Proxy
+	for rows.Next() {
+		p, err := r.scanProxy(rows)
 		if err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+			return nil, err
 		}
-		p.IP = ipStr
 		result = append(result, p)
 	}
-	return result, nil
+	return result, rows.Err()
+}
+
+// SaveResolutions stamps resolved_at on every proxy. A proxy whose address
+// changed gets the new one, is queued for a check straight away, and the
+// change is added to proxy_resolutions. It stops being served until the new
+// address has earned its own success streak. Failed lookups keep the old
+// address.
+func (r *PostgresRepository) SaveResolutions(ctx context.Context, resolutions []*model.Resolution) error {
+	batch := &pgx.Batch{}
+	queued := 0
+	for _, res := range resolutions {
+		if !res.Changed() {
+			batch.Queue(`UPDATE proxies SET resolved_at = $1 WHERE id = $2`, res.ResolvedAt, res.ProxyID)
+			queued++
+			continue
+		}
+		batch.Queue(`
+			UPDATE proxies SET ip = $1, resolved_at = $2, last_checked_at = NULL,
+				promoted_at = NULL, success_streak = 0
+			WHERE id = $3
+		`, res.IP, res.ResolvedAt, res.ProxyID)
+		batch.Queue(`
+			INSERT INTO proxy_resolutions (proxy_id, ip, resolved_at)
+			VALUES ($1, $2, $3)
+		`, res.ProxyID, res.IP, res.ResolvedAt)
+		queued += 2
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < queued; i++ {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to save resolution %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// PruneChecks deletes check history older than before.
+func (r *PostgresRepository) PruneChecks(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM proxy_checks WHERE checked_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("prune checks failed: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// updateSQL writes the result of a check. Arguments come from updateArgs.
+// The check only counts if the proxy still has the address and credentials
+// it was checked with: a re-resolution or a scrape with new credentials
+// while the check ran has queued it for a fresh one already.
+const updateSQL = `
+	UPDATE proxies
+	SET latency_ms = $1, last_checked_at = $2, country = $3, protocol = $4,
+		city = $5, region = $6, latitude = $7, longitude = $8,
+		asn = NULLIF($9, 0), asn_org = $10, network_type = $11,
+		exit_ip = NULLIF($12, '')::INET, anonymity = NULLIF($13, ''),
+		supports_https = $14, tampered = $15, tamper_reason = NULLIF($16, ''),
+		throughput_kbps = NULLIF($17, 0), last_failure = NULLIF($18, ''),
+		success_streak = $19, promoted_at = $20, supports_ipv6 = $21,
+		last_alive_at = $22, check_claimed_until = NULL
+	WHERE id = $23 AND ip IS NOT DISTINCT FROM NULLIF($24, '')::INET
+		AND auth_hash IS NOT DISTINCT FROM $25
+`
+
+func updateArgs(p *model.Proxy) []any {
+	return []any{
+		p.LatencyMS, p.LastCheckedAt, p.Country, p.Protocol,
+		p.City, p.Region, p.Latitude, p.Longitude,
+		p.ASN, p.ASNOrg, p.NetworkType,
+		p.ExitIP, p.Anonymity,
+		p.SupportsHTTPS, p.Tampered, p.TamperReason,
+		p.ThroughputKBps, p.LastFailure,
+		p.SuccessStreak, p.PromotedAt, p.SupportsIPv6,
+		p.LastAliveAt,
+		p.ID,
+		p.IP, p.AuthHash,
+	}
 }
 
-// Update updates a single proxy's status.
+// Update updates a single proxy's status. It returns ErrStale if the
+// proxy's address or credentials changed since it was read.
 func (r *PostgresRepository) Update(ctx context.Context, p *model.Proxy) error {
-	query := `
-		UPDATE proxies 
-		SET latency_ms = $1, last_checked_at = $2, country = $3
-		WHERE id = $4
-	`
-	_, err := r.pool.Exec(ctx, query, p.LatencyMS, p.LastCheckedAt, p.Country, p.ID)
+	tag, err := r.pool.Exec(ctx, updateSQL, updateArgs(p)...)
 	if err != nil {
 		return fmt.Errorf("update failed: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrStale
+	}
 	return nil
 }
 
-// UpdateBatch updates multiple proxies efficiently using a batch.
+// UpdateBatch updates multiple proxies efficiently using a batch. Results
+// for proxies whose address or credentials changed since they were read
+// are dropped.
 func (r *PostgresRepository) UpdateBatch(ctx context.Context, proxies []*model.Proxy) error {
 	batch := &pgx.Batch{}
 	for _, p := range proxies {
-		batch.Queue(`
-			UPDATE proxies 
-			SET latency_ms = $1, last_checked_at = $2, country = $3, protocol = $4
-			WHERE id = $5
-		`, p.LatencyMS, p.LastCheckedAt, p.Country, p.Protocol, p.ID)
+		batch.Queue(updateSQL, updateArgs(p)...)
 	}
 
 	br := r.pool.SendBatch(ctx, batch)
 	defer br.Close()
 
+	stale := 0
 	for i := 0; i < len(proxies); i++ {
-		_, err := br.Exec()
+		tag, err := br.Exec()
 		if err != nil {
 			return fmt.Errorf("failed to update batch item %d: %w", i, err)
 		}
+		if tag.RowsAffected() == 0 {
+			stale++
+		}
+	}
+	if stale > 0 {
+		slog.Debug("Dropped stale check results", "count", stale)
 	}
 	return nil
 }
 
+// GetProxy returns the proxy with the given ID, or ErrNotFound.
+func (r *PostgresRepository) GetProxy(ctx context.Context, id int64) (*model.Proxy, error) {
+	row := r.pool.QueryRow(ctx, "SELECT "+proxyColumns+" FROM proxies WHERE id = $1", id)
+	p, err := r.scanProxy(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
+
 // Count returns the total number of proxies.
 func (r *PostgresRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
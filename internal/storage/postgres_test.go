@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -16,13 +18,13 @@ func setupTestDB(t *testing.T) *PostgresRepository {
 	// Load env from project root
 	// Assuming test is running from inside internal/storage so we look up 2 levels
 	_ = godotenv.Load("../../.env")
-	
+
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
 		t.Skip("DATABASE_URL not set, skipping integration test")
 	}
 
-	repo, err := NewPostgresRepository(dbURL)
+	repo, err := NewPostgresRepository(dbURL, nil)
 	if err != nil {
 		t.Fatalf("Failed to connect to DB: %v", err)
 	}
@@ -56,7 +58,7 @@ func TestSaveAndGet(t *testing.T) {
 	// Verify insertion using direct query (since GetProxiesToCheck might return other pending proxies)
 	var id int64
 	query := `SELECT id FROM proxies WHERE ip=$1 AND port=$2`
-	// We need to use the pool to query. Accessing private field 'pool'? 
+	// We need to use the pool to query. Accessing private field 'pool'?
 	// The test is in 'storage' package, so it has access to 'pool' if it's in the same package scope.
 	// postgres_test.go is package storage.
 	err = repo.pool.QueryRow(ctx, query, ip, port).Scan(&id)
@@ -66,10 +68,10 @@ func TestSaveAndGet(t *testing.T) {
 	proxies[0].ID = id
 
 	// 3. GetUnchecked
-	// We verify it returns *something* if DB is populated, 
+	// We verify it returns *something* if DB is populated,
 	// and specifically check if our proxy is there IF the DB was empty (hard to guarantee).
 	// So we just check usage.
-	toCheck, err := repo.GetProxiesToCheck(ctx, 10)
+	toCheck, err := repo.GetProxiesToCheck(ctx, LaneNew, time.Now(), 10)
 	if err != nil {
 		t.Fatalf("GetProxiesToCheck failed: %v", err)
 	}
@@ -86,7 +88,7 @@ func TestSaveAndGet(t *testing.T) {
 	proxies[0].LatencyMS = 150
 	proxies[0].LastCheckedAt = &now
 	proxies[0].Country = "ID"
-	
+
 	err = repo.UpdateBatch(ctx, proxies)
 	if err != nil {
 		t.Fatalf("UpdateBatch failed: %v", err)
@@ -94,3 +96,119 @@ func TestSaveAndGet(t *testing.T) {
 
 	fmt.Println("Test passed: Inserted, verified, and Batch Updated proxy.")
 }
+
+// promote records a passed check on p as the engine would.
+func promote(p *model.Proxy) {
+	now := time.Now()
+	p.LatencyMS = 100
+	p.LastCheckedAt = &now
+	p.LastAliveAt = &now
+	p.SuccessStreak = 3
+	p.PromotedAt = &now
+}
+
+// A check that was running with the old credentials when a scrape brought
+// new ones must not put the proxy back into service.
+func TestUpdate_StaleAfterCredentialsChange(t *testing.T) {
+	repo := setupTestDB(t)
+	defer repo.Close()
+	repo.cipher, _ = NewCipher(bytes.Repeat([]byte{7}, 32))
+	ctx := context.Background()
+
+	rnd := int(time.Now().UnixNano() % 10000)
+	listed := &model.Proxy{IP: fmt.Sprintf("127.1.%d.1", rnd%255), Port: 8000 + rnd, Protocol: "http", Username: "user", Password: "old"}
+	if err := repo.SaveBatch(ctx, []*model.Proxy{listed}); err != nil {
+		t.Fatalf("SaveBatch failed: %v", err)
+	}
+	var id int64
+	if err := repo.pool.QueryRow(ctx, `SELECT id FROM proxies WHERE ip = $1 AND port = $2`, listed.IP, listed.Port).Scan(&id); err != nil {
+		t.Fatalf("Inserted proxy not found: %v", err)
+	}
+
+	// The same credentials listed again are no change.
+	checked, err := repo.GetProxy(ctx, id)
+	if err != nil {
+		t.Fatalf("GetProxy failed: %v", err)
+	}
+	if err := repo.SaveBatch(ctx, []*model.Proxy{listed}); err != nil {
+		t.Fatalf("SaveBatch failed: %v", err)
+	}
+	promote(checked)
+	if err := repo.Update(ctx, checked); err != nil {
+		t.Fatalf("Update with unchanged credentials failed: %v", err)
+	}
+
+	// New credentials while the next check runs.
+	checked, _ = repo.GetProxy(ctx, id)
+	listed.Password = "new"
+	if err := repo.SaveBatch(ctx, []*model.Proxy{listed}); err != nil {
+		t.Fatalf("SaveBatch failed: %v", err)
+	}
+	promote(checked)
+	if err := repo.Update(ctx, checked); !errors.Is(err, ErrStale) {
+		t.Fatalf("Update = %v, want ErrStale", err)
+	}
+	if err := repo.UpdateBatch(ctx, []*model.Proxy{checked}); err != nil {
+		t.Fatalf("UpdateBatch failed: %v", err)
+	}
+
+	got, _ := repo.GetProxy(ctx, id)
+	if got.PromotedAt != nil || got.SuccessStreak != 0 || got.LastCheckedAt != nil {
+		t.Errorf("Stale check was written: promoted %v, streak %d, checked %v", got.PromotedAt, got.SuccessStreak, got.LastCheckedAt)
+	}
+	if got.Password != "new" {
+		t.Errorf("Password = %q, want the new one", got.Password)
+	}
+}
+
+// A check of a hostname proxy's old address must not put it back into
+// service once it resolves elsewhere.
+func TestUpdate_StaleAfterResolution(t *testing.T) {
+	repo := setupTestDB(t)
+	defer repo.Close()
+	ctx := context.Background()
+
+	rnd := int(time.Now().UnixNano() % 10000)
+	host := fmt.Sprintf("stale-%d.proxypool.test", rnd)
+	if err := repo.SaveBatch(ctx, []*model.Proxy{{Host: host, Port: 8000 + rnd, Protocol: "http"}}); err != nil {
+		t.Fatalf("SaveBatch failed: %v", err)
+	}
+	var id int64
+	if err := repo.pool.QueryRow(ctx, `SELECT id FROM proxies WHERE host = $1`, host).Scan(&id); err != nil {
+		t.Fatalf("Inserted proxy not found: %v", err)
+	}
+
+	resolve := func(ip, previous string) {
+		t.Helper()
+		err := repo.SaveResolutions(ctx, []*model.Resolution{{ProxyID: id, IP: ip, PreviousIP: previous, ResolvedAt: time.Now()}})
+		if err != nil {
+			t.Fatalf("SaveResolutions failed: %v", err)
+		}
+	}
+	resolve("198.51.100.7", "")
+
+	checked, err := repo.GetProxy(ctx, id)
+	if err != nil {
+		t.Fatalf("GetProxy failed: %v", err)
+	}
+	resolve("198.51.100.8", "198.51.100.7")
+	promote(checked)
+	if err := repo.UpdateBatch(ctx, []*model.Proxy{checked}); err != nil {
+		t.Fatalf("UpdateBatch failed: %v", err)
+	}
+
+	got, _ := repo.GetProxy(ctx, id)
+	if got.IP != "198.51.100.8" {
+		t.Errorf("IP = %q, want the new resolution", got.IP)
+	}
+	if got.PromotedAt != nil || got.SuccessStreak != 0 || got.LastCheckedAt != nil {
+		t.Errorf("Stale check was written: promoted %v, streak %d, checked %v", got.PromotedAt, got.SuccessStreak, got.LastCheckedAt)
+	}
+
+	// A check of the current address counts.
+	checked = got
+	promote(checked)
+	if err := repo.Update(ctx, checked); err != nil {
+		t.Fatalf("Update of the current address failed: %v", err)
+	}
+}
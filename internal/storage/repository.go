@@ -2,25 +2,92 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"proxypool/internal/model"
 )
 
+var (
+	// ErrNotFound means no proxy has the given ID.
+	ErrNotFound = errors.New("proxy not found")
+	// ErrStale means a check result was dropped because the proxy's
+	// address or credentials changed while it ran.
+	ErrStale = errors.New("proxy changed during the check")
+)
+
 // ProxyRepository defines the methods for interacting with the proxy storage.
 type ProxyRepository interface {
 	// SaveBatch saves a batch of proxies. It should handle duplicates (e.g., ON CONFLICT DO NOTHING).
 	SaveBatch(ctx context.Context, proxies []*model.Proxy) error
 
-	// GetUnchecked returns a list of proxies that need to be checked.
-	// This should include proxies that have never been checked OR haven't been checked in a while.
-	GetProxiesToCheck(ctx context.Context, limit int) ([]*model.Proxy, error)
+	// GetProxiesToCheck claims and returns up to limit proxies of a check
+	// lane (Lane*) that were never checked or last checked before
+	// checkedBefore, the longest waiting first. A claimed proxy isn't
+	// returned again until its check is written with Update(Batch) or the
+	// claim expires, so several instances can share the checking.
+	GetProxiesToCheck(ctx context.Context, lane string, checkedBefore time.Time, limit int) ([]*model.Proxy, error)
 
-	// Update updates the validation status (latency, anonymity, etc.) of a proxy.
+	// Update updates the validation status (latency, anonymity, etc.) of a
+	// proxy, or returns ErrStale if its address or credentials changed
+	// since it was read.
 	Update(ctx context.Context, proxy *model.Proxy) error
 
-	// UpdateBatch updates a batch of proxies.
+	// UpdateBatch updates a batch of proxies, dropping stale ones like Update.
 	UpdateBatch(ctx context.Context, proxies []*model.Proxy) error
-	
+
+	// List returns alive proxies matching the filter, best first (see ProxyFilter.Sort).
+	List(ctx context.Context, filter ProxyFilter) ([]*model.Proxy, error)
+
+	// ListPage returns up to limit proxies with ID greater than afterID, in ID order.
+	ListPage(ctx context.Context, afterID int64, limit int) ([]*model.Proxy, error)
+
+	// UpdateGeoBatch writes only the GeoIP derived fields of the proxies.
+	UpdateGeoBatch(ctx context.Context, proxies []*model.Proxy) error
+
+	// GetProxiesForProfile returns up to limit alive proxies whose result for
+	// profile is missing or older than staleBefore.
+	GetProxiesForProfile(ctx context.Context, profile string, staleBefore time.Time, limit int) ([]*model.Proxy, error)
+
+	// SaveProfileResults stores the latest profile result per proxy.
+	SaveProfileResults(ctx context.Context, results []*model.ProfileResult) error
+
+	// GetProxiesForRegion claims and returns up to limit alive proxies
+	// whose result from region is missing or older than staleBefore, for a
+	// checker agent there.
+	GetProxiesForRegion(ctx context.Context, region string, staleBefore time.Time, limit int) ([]*model.Proxy, error)
+
+	// SaveRegionResults stores the latest result per proxy and region.
+	SaveRegionResults(ctx context.Context, results []*model.RegionResult) error
+
+	// SaveChecks appends entries to the check history.
+	SaveChecks(ctx context.Context, checks []*model.CheckRecord) error
+
+	// FailureStats counts failed checks since the given time, and currently
+	// dead proxies, by failure class.
+	FailureStats(ctx context.Context, since time.Time) (*FailureStats, error)
+
+	// GetProxiesToResolve returns up to limit hostname proxies that were
+	// never resolved or last resolved before staleBefore.
+	GetProxiesToResolve(ctx context.Context, staleBefore time.Time, limit int) ([]*model.Proxy, error)
+
+	// SaveResolutions records hostname lookups, updating the proxies'
+	// addresses and keeping a history of changes.
+	SaveResolutions(ctx context.Context, resolutions []*model.Resolution) error
+
+	// PruneChecks deletes check history older than before and returns how many rows went.
+	PruneChecks(ctx context.Context, before time.Time) (int64, error)
+
+	// GetProxy returns the proxy with the given ID, or ErrNotFound.
+	GetProxy(ctx context.Context, id int64) (*model.Proxy, error)
+
 	// Count returns the total number of proxies.
 	Count(ctx context.Context) (int64, error)
 }
+
+// FailureStats breaks failures down by class (checker.Failure*).
+type FailureStats struct {
+	Since  time.Time        `json:"since"`
+	Window map[string]int64 `json:"window"` // Failed checks since Since
+	Dead   map[string]int64 `json:"dead"`   // Proxies whose latest check failed, by its reason
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Cipher encrypts proxy credentials at rest with AES-256-GCM.
+// Ciphertexts are nonce || sealed data.
+type Cipher struct {
+	aead   cipher.AEAD
+	macKey []byte // for Fingerprint, derived from the key
+}
+
+// NewCipher creates a Cipher from a 32 byte key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("credentials key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("gcm: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("proxypool credentials fingerprint"))
+	return &Cipher{aead: aead, macKey: mac.Sum(nil)}, nil
+}
+
+// NewCipherFromBase64 decodes a base64 key (as stored in the environment).
+func NewCipherFromBase64(key string) (*Cipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decode credentials key: %w", err)
+	}
+	return NewCipher(raw)
+}
+
+// Encrypt seals plaintext. Empty strings stay empty (nil) so that "no
+// credentials" remains NULL in the database.
+func (c *Cipher) Encrypt(plaintext string) ([]byte, error) {
+	if plaintext == "" {
+		return nil, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+// Fingerprint returns a keyed hash of the credentials. Unlike the
+// ciphertexts it is the same every time, so stored credentials can be
+// compared without decrypting them.
+func (c *Cipher) Fingerprint(username, password string) []byte {
+	mac := hmac.New(sha256.New, c.macKey)
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+// Decrypt opens a value produced by Encrypt.
+func (c *Cipher) Decrypt(ciphertext []byte) (string, error) {
+	if len(ciphertext) == 0 {
+		return "", nil
+	}
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
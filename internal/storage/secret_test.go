@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+
+	enc, err := c.Encrypt("s3cret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Contains(enc, []byte("s3cret")) {
+		t.Errorf("Ciphertext contains plaintext")
+	}
+
+	dec, err := c.Decrypt(enc)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if dec != "s3cret" {
+		t.Errorf("Decrypt = %q, want %q", dec, "s3cret")
+	}
+
+	// Empty values map to NULL and back.
+	if enc, _ := c.Encrypt(""); enc != nil {
+		t.Errorf("Expected nil ciphertext for empty string")
+	}
+	if dec, _ := c.Decrypt(nil); dec != "" {
+		t.Errorf("Expected empty plaintext for nil ciphertext")
+	}
+
+	// Tampering is detected.
+	enc[len(enc)-1] ^= 1
+	if _, err := c.Decrypt(enc); err == nil {
+		t.Errorf("Expected error for tampered ciphertext")
+	}
+}
+
+func TestNewCipher_BadKey(t *testing.T) {
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Errorf("Expected error for short key")
+	}
+}
+
+func TestCipher_Fingerprint(t *testing.T) {
+	c, _ := NewCipher(bytes.Repeat([]byte{7}, 32))
+	other, _ := NewCipher(bytes.Repeat([]byte{8}, 32))
+
+	fp := c.Fingerprint("user", "pass")
+	if !bytes.Equal(fp, c.Fingerprint("user", "pass")) {
+		t.Error("Fingerprint should be stable")
+	}
+	for _, creds := range [][2]string{{"user", "pass2"}, {"user2", "pass"}, {"userp", "ass"}} {
+		if bytes.Equal(fp, c.Fingerprint(creds[0], creds[1])) {
+			t.Errorf("Fingerprint of %v matches user/pass", creds)
+		}
+	}
+	if bytes.Equal(fp, other.Fingerprint("user", "pass")) {
+		t.Error("Fingerprint should depend on the key")
+	}
+}